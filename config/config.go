@@ -42,15 +42,103 @@ type NotionConfig struct {
 	ClientSecret string `mapstructure:"client_secret"`
 	RedirectURI  string `mapstructure:"redirect_uri"`
 	StateSecret  string `mapstructure:"state_secret"`
+
+	LinkPreviews LinkPreviewConfig `mapstructure:"link_previews"`
+}
+
+// LinkPreviewConfig picks the Notion block (bookmark, embed, video or
+// paragraph) for links that stand alone in a paragraph. Teams, keyed by
+// Notion workspace ID, can add their own rules and fallback.
+type LinkPreviewConfig struct {
+	Rules    []LinkPreviewRule          `mapstructure:"rules"`
+	Fallback string                     `mapstructure:"fallback"`
+	Teams    map[string]LinkPreviewTeam `mapstructure:"teams"`
+}
+
+type LinkPreviewTeam struct {
+	Rules    []LinkPreviewRule `mapstructure:"rules"`
+	Fallback string            `mapstructure:"fallback"`
+}
+
+type LinkPreviewRule struct {
+	Host      string `mapstructure:"host"`      // also matches subdomains
+	Extension string `mapstructure:"extension"` // e.g. ".mp4"
+	Block     string `mapstructure:"block"`
+}
+
+type MarkdownConfig struct {
+	// Extensions names the registered custom Markdown extensions to enable.
+	Extensions []string `mapstructure:"extensions"`
+}
+
+// LintConfig turns lint rules on or off or changes their severity, keyed by
+// rule ID. A value is "off", "on" or a severity (info, warning, error).
+// FailOn is the lowest severity that stops a draft from being staged, or
+// "none". Dictionary lists words the spelling rule accepts, such as product
+// names and jargon, as Hunspell .dic entries ("Petrel/M"); the rule is off
+// unless a rule set turns it on. Teams, keyed by Notion workspace ID, and
+// users, keyed by user ID, can override the defaults and add to the
+// glossary and dictionary.
+type LintConfig struct {
+	Rules       map[string]string         `mapstructure:"rules"`
+	FailOn      string                    `mapstructure:"fail_on"`
+	Glossary    GlossaryConfig            `mapstructure:"glossary"`
+	Dictionary  []string                  `mapstructure:"dictionary"`
+	Readability ReadabilityConfig         `mapstructure:"readability"`
+	Teams       map[string]LintRuleConfig `mapstructure:"teams"`
+	Users       map[string]LintRuleConfig `mapstructure:"users"`
+}
+
+type LintRuleConfig struct {
+	Rules       map[string]string `mapstructure:"rules"`
+	FailOn      string            `mapstructure:"fail_on"`
+	Glossary    GlossaryConfig    `mapstructure:"glossary"`
+	Dictionary  []string          `mapstructure:"dictionary"`
+	Readability ReadabilityConfig `mapstructure:"readability"`
+}
+
+// ReadabilityConfig sets the limits the readability-grade and draft-length
+// rules check: a Flesch-Kincaid grade level and a word count. Zero leaves
+// a limit unset.
+type ReadabilityConfig struct {
+	MaxGrade float64 `mapstructure:"max_grade"`
+	MaxWords int     `mapstructure:"max_words"`
+}
+
+// GlossaryConfig is a team's terminology: banned words, preferred terms
+// (e.g. "sign in" for "login"), product names in their proper case and
+// overused phrases on top of the built-in ones. Terms are lists rather
+// than maps so they keep their case and may contain dots.
+type GlossaryConfig struct {
+	Banned    []GlossaryTermConfig `mapstructure:"banned"`
+	Preferred []GlossaryTermConfig `mapstructure:"preferred"`
+	Products  []string             `mapstructure:"products"`
+	Overused  []GlossaryTermConfig `mapstructure:"overused"`
+}
+
+type GlossaryTermConfig struct {
+	Term        string `mapstructure:"term"`
+	Replacement string `mapstructure:"replacement"`
+}
+
+// SensitiveContentConfig says what staging does with personal data and
+// secrets: "warn", "redact" or "block". Kinds (email, phone, national_id,
+// credit_card, api_key, private_key, jwt, secret) override Action.
+type SensitiveContentConfig struct {
+	Action string            `mapstructure:"action"`
+	Kinds  map[string]string `mapstructure:"kinds"`
 }
 
 type AppConfig struct {
-	Env    string       `mapstructure:"env"`
-	Port   string       `mapstructure:"port"`
-	DB     DBConfig     `mapstructure:"db"`
-	Notion NotionConfig `mapstructure:"notion"`
-	Auth0  Auth0Config  `mapstructure:"auth0"`
-	CORS   CORSConfig   `mapstructure:"cors"`
+	Env              string                 `mapstructure:"env"`
+	Port             string                 `mapstructure:"port"`
+	DB               DBConfig               `mapstructure:"db"`
+	Notion           NotionConfig           `mapstructure:"notion"`
+	Auth0            Auth0Config            `mapstructure:"auth0"`
+	CORS             CORSConfig             `mapstructure:"cors"`
+	Markdown         MarkdownConfig         `mapstructure:"markdown"`
+	Lint             LintConfig             `mapstructure:"lint"`
+	SensitiveContent SensitiveContentConfig `mapstructure:"sensitive_content"`
 }
 
 var (
@@ -0,0 +1 @@
+ALTER TABLE notion_drafts DROP COLUMN IF EXISTS metrics;
@@ -0,0 +1,2 @@
+-- Length and readability metrics computed when a draft is staged
+ALTER TABLE notion_drafts ADD COLUMN metrics JSONB;
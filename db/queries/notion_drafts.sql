@@ -7,9 +7,10 @@ INSERT INTO notion_drafts (
     published_page_id,
     title,
     status,
-    is_orphaned
+    is_orphaned,
+    metrics
 ) VALUES (
-             $1, $2, $3, $4, $5, $6, $7, $8
+             $1, $2, $3, $4, $5, $6, $7, $8, $9
          )
     RETURNING *;
 
@@ -1,11 +1,14 @@
 package manuscript
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/obi2na/petrel/internal/logger"
 	"github.com/obi2na/petrel/internal/models"
+	utils "github.com/obi2na/petrel/internal/pkg"
 	"github.com/obi2na/petrel/internal/service/manuscript"
+	"github.com/obi2na/petrel/internal/service/notion"
 	"go.uber.org/zap"
 	"net/http"
 )
@@ -17,6 +20,7 @@ func RegisterManuscriptRoutes(r *gin.RouterGroup, manuscriptSvc manuscript.Servi
 
 	//register routes
 	r.POST("/draft", manuscriptHandler.CreateDraft)
+	r.POST("/lint", manuscriptHandler.Lint)
 
 }
 
@@ -55,6 +59,26 @@ func (h *ManuscriptHandler) CreateDraft(c *gin.Context) {
 
 	// TODO: finish implementing service
 	resp, err := h.Service.StageDraft(ctx, userID, req)
+	if errors.Is(err, notion.ErrContentDropped) {
+		logger.With(ctx).Warn("draft rejected in strict mode", zap.Error(err))
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "draft content could not be fully mapped", "details": err.Error(), "body": resp})
+		return
+	}
+	if errors.Is(err, manuscript.ErrLintFailed) {
+		logger.With(ctx).Warn("draft blocked by lint findings", zap.Error(err))
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "draft has blocking lint findings", "details": err.Error(), "body": resp})
+		return
+	}
+	if errors.Is(err, manuscript.ErrSensitiveContent) {
+		logger.With(ctx).Warn("draft blocked by sensitive content policy", zap.Error(err))
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "draft contains sensitive content", "details": err.Error(), "body": resp})
+		return
+	}
+	if errors.Is(err, utils.ErrInvalidLintRules) || errors.Is(err, manuscript.ErrInvalidMarkdown) || errors.Is(err, manuscript.ErrMissingFields) {
+		logger.With(ctx).Error("invalid draft request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid draft request", "details": err.Error()})
+		return
+	}
 	if err != nil {
 		logger.With(ctx).Error("failed to create draft", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create draft", "details": err.Error()})
@@ -65,3 +89,40 @@ func (h *ManuscriptHandler) CreateDraft(c *gin.Context) {
 		"body":    resp,
 	})
 }
+
+// Lint returns lint findings and fixes for Markdown without creating a draft.
+func (h *ManuscriptHandler) Lint(c *gin.Context) {
+
+	ctx := c.Request.Context()
+
+	userIDRaw, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user ID not found"})
+		return
+	}
+	userID, ok := userIDRaw.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user ID format"})
+		return
+	}
+
+	var req petrelmodels.LintRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.With(ctx).Error("invalid payload", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload", "details": err.Error()})
+		return
+	}
+
+	resp, err := h.Service.Lint(ctx, userID, req)
+	if errors.Is(err, utils.ErrInvalidLintRules) || errors.Is(err, manuscript.ErrInvalidMarkdown) {
+		logger.With(ctx).Error("invalid lint request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid lint request", "details": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.With(ctx).Error("failed to lint markdown", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to lint markdown", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,134 @@
+package manuscript
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/obi2na/petrel/internal/logger"
+	"github.com/obi2na/petrel/internal/models"
+	utils "github.com/obi2na/petrel/internal/pkg"
+	"github.com/obi2na/petrel/internal/service/manuscript"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type MockManuscriptService struct {
+	mock.Mock
+}
+
+func (m *MockManuscriptService) StageDraft(ctx context.Context, userID uuid.UUID, req petrelmodels.CreateDraftRequest) (petrelmodels.CreateDraftResponse, error) {
+	args := m.Called(ctx, userID, req)
+	return args.Get(0).(petrelmodels.CreateDraftResponse), args.Error(1)
+}
+
+func (m *MockManuscriptService) Lint(ctx context.Context, userID uuid.UUID, req petrelmodels.LintRequest) (petrelmodels.LintResponse, error) {
+	args := m.Called(ctx, userID, req)
+	return args.Get(0).(petrelmodels.LintResponse), args.Error(1)
+}
+
+func TestLint(t *testing.T) {
+
+	// initialize logger so code doesn't panic.
+	logger.Init()
+
+	userID := uuid.New()
+	clean := petrelmodels.LintResponse{Warnings: []utils.LintWarning{}, FixedMarkdown: "# Title\n"}
+
+	tests := []struct {
+		name             string
+		reqBody          string
+		withUser         bool
+		serviceResp      petrelmodels.LintResponse
+		serviceErr       error
+		expectedRespCode int
+	}{
+		{
+			name:             "lints markdown",
+			reqBody:          `{"markdown": "# Title\n", "workspace_id": "ws-1"}`,
+			withUser:         true,
+			serviceResp:      clean,
+			expectedRespCode: http.StatusOK,
+		},
+		{
+			name:             "missing markdown",
+			reqBody:          `{"workspace_id": "ws-1"}`,
+			withUser:         true,
+			expectedRespCode: http.StatusBadRequest,
+		},
+		{
+			name:             "invalid lint rules",
+			reqBody:          `{"markdown": "# Title\n", "lint_rules": {"todo": "loud"}}`,
+			withUser:         true,
+			serviceErr:       fmt.Errorf("%w: rule \"todo\"", utils.ErrInvalidLintRules),
+			expectedRespCode: http.StatusBadRequest,
+		},
+		{
+			name:             "invalid markdown",
+			reqBody:          `{"markdown": "---\ntitle: [\n---\n"}`,
+			withUser:         true,
+			serviceErr:       fmt.Errorf("%w: invalid front matter", manuscript.ErrInvalidMarkdown),
+			expectedRespCode: http.StatusBadRequest,
+		},
+		{
+			name:             "no user",
+			reqBody:          `{"markdown": "# Title\n"}`,
+			expectedRespCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := new(MockManuscriptService)
+			mockService.On("Lint", mock.Anything, userID, mock.Anything).Return(tc.serviceResp, tc.serviceErr)
+
+			router := gin.Default()
+			router.POST("/lint", func(c *gin.Context) {
+				if tc.withUser {
+					c.Set("user_id", userID)
+				}
+				NewManuscriptHandler(mockService).Lint(c)
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodPost, "/lint", bytes.NewBufferString(tc.reqBody))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedRespCode, w.Code)
+			if tc.expectedRespCode == http.StatusOK {
+				var resp petrelmodels.LintResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, tc.serviceResp, resp)
+			}
+		})
+	}
+}
+
+func TestCreateDraft_MissingFields(t *testing.T) {
+	logger.Init()
+	userID := uuid.New()
+	svc := &manuscript.ManuscriptService{Parser: utils.NewDefaultMarkdownParser()}
+
+	router := gin.Default()
+	router.POST("/draft", func(c *gin.Context) {
+		c.Set("user_id", userID)
+		NewManuscriptHandler(svc).CreateDraft(c)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/draft", bytes.NewBufferString(`{"markdown": "# Plan\n\nShip it.\n"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var resp map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Contains(t, resp["details"], "title and destinations required")
+}
@@ -78,6 +78,7 @@ type NotionDraft struct {
 	IsOrphaned          pgtype.Bool      `json:"is_orphaned"`
 	CreatedAt           pgtype.Timestamp `json:"created_at"`
 	UpdatedAt           pgtype.Timestamp `json:"updated_at"`
+	Metrics             []byte           `json:"metrics"`
 }
 
 type NotionIntegration struct {
@@ -21,11 +21,12 @@ INSERT INTO notion_drafts (
     published_page_id,
     title,
     status,
-    is_orphaned
+    is_orphaned,
+    metrics
 ) VALUES (
-             $1, $2, $3, $4, $5, $6, $7, $8
+             $1, $2, $3, $4, $5, $6, $7, $8, $9
          )
-    RETURNING id, user_id, notion_integration_id, notion_page_id, published_page_id, title, status, is_orphaned, created_at, updated_at
+    RETURNING id, user_id, notion_integration_id, notion_page_id, published_page_id, title, status, is_orphaned, created_at, updated_at, metrics
 `
 
 type CreateNotionDraftParams struct {
@@ -37,6 +38,7 @@ type CreateNotionDraftParams struct {
 	Title               pgtype.Text     `json:"title"`
 	Status              NullDraftStatus `json:"status"`
 	IsOrphaned          pgtype.Bool     `json:"is_orphaned"`
+	Metrics             []byte          `json:"metrics"`
 }
 
 func (q *Queries) CreateNotionDraft(ctx context.Context, arg CreateNotionDraftParams) (NotionDraft, error) {
@@ -49,6 +51,7 @@ func (q *Queries) CreateNotionDraft(ctx context.Context, arg CreateNotionDraftPa
 		arg.Title,
 		arg.Status,
 		arg.IsOrphaned,
+		arg.Metrics,
 	)
 	var i NotionDraft
 	err := row.Scan(
@@ -62,12 +65,13 @@ func (q *Queries) CreateNotionDraft(ctx context.Context, arg CreateNotionDraftPa
 		&i.IsOrphaned,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Metrics,
 	)
 	return i, err
 }
 
 const getNotionDraftByID = `-- name: GetNotionDraftByID :one
-SELECT id, user_id, notion_integration_id, notion_page_id, published_page_id, title, status, is_orphaned, created_at, updated_at FROM notion_drafts
+SELECT id, user_id, notion_integration_id, notion_page_id, published_page_id, title, status, is_orphaned, created_at, updated_at, metrics FROM notion_drafts
 WHERE id = $1
 `
 
@@ -85,12 +89,13 @@ func (q *Queries) GetNotionDraftByID(ctx context.Context, id uuid.UUID) (NotionD
 		&i.IsOrphaned,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Metrics,
 	)
 	return i, err
 }
 
 const getNotionDraftByPageID = `-- name: GetNotionDraftByPageID :one
-SELECT id, user_id, notion_integration_id, notion_page_id, published_page_id, title, status, is_orphaned, created_at, updated_at FROM notion_drafts
+SELECT id, user_id, notion_integration_id, notion_page_id, published_page_id, title, status, is_orphaned, created_at, updated_at, metrics FROM notion_drafts
 WHERE notion_page_id = $1
 `
 
@@ -108,6 +113,7 @@ func (q *Queries) GetNotionDraftByPageID(ctx context.Context, notionPageID strin
 		&i.IsOrphaned,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Metrics,
 	)
 	return i, err
 }
@@ -134,7 +140,7 @@ func (q *Queries) IsValidNotionDraftPage(ctx context.Context, arg IsValidNotionD
 }
 
 const listNotionDraftsForUser = `-- name: ListNotionDraftsForUser :many
-SELECT id, user_id, notion_integration_id, notion_page_id, published_page_id, title, status, is_orphaned, created_at, updated_at FROM notion_drafts
+SELECT id, user_id, notion_integration_id, notion_page_id, published_page_id, title, status, is_orphaned, created_at, updated_at, metrics FROM notion_drafts
 WHERE user_id = $1
   AND is_orphaned = false
 ORDER BY created_at DESC
@@ -160,6 +166,7 @@ func (q *Queries) ListNotionDraftsForUser(ctx context.Context, userID uuid.UUID)
 			&i.IsOrphaned,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Metrics,
 		); err != nil {
 			return nil, err
 		}
@@ -172,7 +179,7 @@ func (q *Queries) ListNotionDraftsForUser(ctx context.Context, userID uuid.UUID)
 }
 
 const listOrphanedNotionDrafts = `-- name: ListOrphanedNotionDrafts :many
-SELECT id, user_id, notion_integration_id, notion_page_id, published_page_id, title, status, is_orphaned, created_at, updated_at FROM notion_drafts
+SELECT id, user_id, notion_integration_id, notion_page_id, published_page_id, title, status, is_orphaned, created_at, updated_at, metrics FROM notion_drafts
 WHERE is_orphaned = true
 ORDER BY created_at DESC
 `
@@ -197,6 +204,7 @@ func (q *Queries) ListOrphanedNotionDrafts(ctx context.Context) ([]NotionDraft,
 			&i.IsOrphaned,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Metrics,
 		); err != nil {
 			return nil, err
 		}
@@ -1,17 +1,52 @@
 package petrelmodels
 
-import "github.com/obi2na/petrel/internal/pkg"
+import (
+	"github.com/google/uuid"
+	"github.com/obi2na/petrel/internal/pkg"
+	"time"
+)
 
+// CreateDraftRequest is a draft to stage. Title, metadata and destinations
+// may instead come from the Markdown's YAML front matter; fields set in the
+// request take precedence.
 type CreateDraftRequest struct {
 	Markdown     string             `json:"markdown" binding:"required"`
-	Title        string             `json:"title" binding:"required"`
+	Title        string             `json:"title,omitempty"`
 	Metadata     *DraftMetadata     `json:"metadata,omitempty"`
-	Destinations []DraftDestination `json:"destinations" binding:"required"`
+	Destinations []DraftDestination `json:"destinations,omitempty"`
+	Strict       bool               `json:"strict,omitempty"` // fail staging if any content cannot be mapped
+	// LintRules turns lint rules on or off or changes their severity for
+	// this draft, on top of the configured rule sets.
+	LintRules utils.LintRuleSet `json:"lint_rules,omitempty"`
+	// FailOn stops staging when lint finds anything this severe or worse:
+	// "info", "warning", "error" or "none". The configured policy still
+	// applies if it is stricter.
+	FailOn string `json:"fail_on,omitempty"`
+}
+
+// LintRequest is Markdown to lint without staging it. WorkspaceID picks
+// the team whose rule set applies, and Platform the destination whose
+// mapper the Markdown is checked against.
+type LintRequest struct {
+	Markdown    string            `json:"markdown" binding:"required"`
+	WorkspaceID string            `json:"workspace_id,omitempty"`
+	Platform    string            `json:"platform,omitempty"` // e.g. "notion"
+	LintRules   utils.LintRuleSet `json:"lint_rules,omitempty"`
+}
+
+// LintResponse holds the findings for the Markdown as sent, and the
+// Markdown with every fix that applies cleanly.
+type LintResponse struct {
+	Warnings      []utils.LintWarning `json:"warnings"`
+	FixedMarkdown string              `json:"fixed_markdown"`
+	FixesApplied  int                 `json:"fixes_applied"`
+	Metrics       utils.DraftMetrics  `json:"metrics"`
 }
 
 type DraftMetadata struct {
-	Source string   `json:"source,omitempty"`
-	Tags   []string `json:"tags,omitempty"`
+	Source    string     `json:"source,omitempty"`
+	Tags      []string   `json:"tags,omitempty"`
+	PublishAt *time.Time `json:"publish_at,omitempty"`
 }
 
 type DraftDestination struct {
@@ -27,15 +62,51 @@ type CreateDraftResponse struct {
 }
 
 type DraftResultEntry struct {
-	DraftID      string              `json:"draft_id"`
-	Platform     string              `json:"platform"`               // e.g. "notion", "confluence"
-	WorkspaceID  string              `json:"workspace_id,omitempty"` // notion workspace or team id
-	PageID       string              `json:"page_id"`                // internal page ID
-	URL          string              `json:"url"`                    // public-facing or redirect-safe URL
-	Status       string              `json:"status"`                 // e.g. "draft"
-	Action       string              `json:"action"`                 // e.g. "created", "appended"
-	ErrorMessage string              `json:"error,omitempty"`        // optional field for partial failures
-	LintWarnings []utils.LintWarning `json:"lint_warnings,omitempty"`
+	DraftID       string              `json:"draft_id"`
+	Platform      string              `json:"platform"`               // e.g. "notion", "confluence"
+	WorkspaceID   string              `json:"workspace_id,omitempty"` // notion workspace or team id
+	PageID        string              `json:"page_id"`                // internal page ID
+	URL           string              `json:"url"`                    // public-facing or redirect-safe URL
+	Status        string              `json:"status"`                 // e.g. "draft"
+	Action        string              `json:"action"`                 // e.g. "created", "appended"
+	ErrorMessage  string              `json:"error,omitempty"`        // optional field for partial failures
+	LintWarnings  []utils.LintWarning `json:"lint_warnings,omitempty"`
+	MappingReport *MappingReport      `json:"mapping_report,omitempty"` // content that did not make it to the platform
+	Metrics       *utils.DraftMetrics `json:"metrics,omitempty"`        // length and readability of the draft
+}
+
+// MappingReport describes the Markdown a platform mapper could not carry over.
+type MappingReport struct {
+	Dropped []DroppedNode  `json:"dropped,omitempty"`
+	Errors  []MappingError `json:"errors,omitempty"`
+	// Warnings flag content that was carried over in a degraded form, e.g.
+	// a wiki-link kept as plain text. They are surfaced with the lint warnings.
+	Warnings []utils.LintWarning `json:"-"`
+}
+
+// DroppedNode is Markdown that was stripped or has no platform equivalent.
+type DroppedNode struct {
+	Kind   string `json:"kind"` // goldmark node kind, e.g. "CodeBlock"
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+}
+
+// MappingError is a node whose mapper failed.
+type MappingError struct {
+	Kind    string `json:"kind"`
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (r MappingReport) Empty() bool {
+	return len(r.Dropped) == 0 && len(r.Errors) == 0
+}
+
+// StageOptions tunes how a platform service stages a draft.
+type StageOptions struct {
+	Title   string              // page title; platforms fall back to a default when empty
+	Strict  bool                // fail instead of staging when the mapping report is not empty
+	Metrics *utils.DraftMetrics // stored with each draft record
 }
 
 type ValidatedDestination struct {
@@ -46,6 +117,7 @@ type ValidatedDestination struct {
 }
 
 type UserIntegration struct {
-	Token        string
-	DraftsRepoID string
+	IntegrationID uuid.UUID // the platform integration's row, which drafts are recorded against
+	Token         string
+	DraftsRepoID  string
 }
@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"fmt"
+	"github.com/yuin/goldmark/ast"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"strings"
+	"unicode"
+)
+
+// ----- Accessibility Rules -----
+
+// vagueLinkTexts say nothing about where a link goes, which is all a
+// screen reader listing the page's links has to go on.
+var vagueLinkTexts = map[string]bool{
+	"click here": true, "click": true, "here": true, "this": true, "this link": true, "this page": true,
+	"link": true, "more": true, "read more": true, "learn more": true, "see more": true, "go": true,
+}
+
+func imageAltRule(n ast.Node, source []byte, lineOffsets []int) []LintWarning {
+	image := n.(*ast.Image)
+	if strings.TrimSpace(plainText(image, source)) != "" {
+		return nil
+	}
+	if offset, ok := inlineOffset(image); ok {
+		return []LintWarning{warningAt(offset, source, lineOffsets, "Image has no alt text")}
+	}
+	return nil
+}
+
+func vagueLinkTextRule(n ast.Node, source []byte, lineOffsets []int) []LintWarning {
+	link := n.(*ast.Link)
+	text := strings.TrimSpace(plainText(link, source))
+	normalized := strings.ToLower(strings.TrimRight(text, ".!:…"))
+	if !vagueLinkTexts[normalized] {
+		return nil
+	}
+	if offset, ok := inlineOffset(link); ok {
+		return []LintWarning{warningAt(offset, source, lineOffsets,
+			fmt.Sprintf("Link text %q does not say where the link goes", text))}
+	}
+	return nil
+}
+
+// bareURLRule flags links whose text is a URL, including autolinks.
+// Screen readers read those out character by character.
+func bareURLRule(n ast.Node, source []byte, lineOffsets []int) []LintWarning {
+	switch link := n.(type) {
+	case *ast.AutoLink:
+		if link.AutoLinkType != ast.AutoLinkURL {
+			return nil
+		}
+	case *ast.Link:
+		text := strings.TrimSpace(plainText(link, source))
+		if text != string(link.Destination) && !looksLikeURL(text) {
+			return nil
+		}
+	}
+	if offset, ok := inlineOffset(n); ok {
+		return []LintWarning{warningAt(offset, source, lineOffsets, "Link text is a bare URL; describe the destination instead")}
+	}
+	return nil
+}
+
+func tableHeaderRule(n ast.Node, source []byte, lineOffsets []int) []LintWarning {
+	table := n.(*extast.Table)
+	header, ok := table.FirstChild().(*extast.TableHeader)
+	if !ok {
+		return nil
+	}
+	for cell := header.FirstChild(); cell != nil; cell = cell.NextSibling() {
+		if strings.TrimSpace(plainText(cell, source)) != "" {
+			return nil
+		}
+	}
+	offset, ok := firstOffset(header)
+	if !ok {
+		return nil
+	}
+	rowStart := lineOffsets[getLine(offset, lineOffsets)-1]
+	return []LintWarning{warningAt(rowStart, source, lineOffsets, "Table has no header row; give each column a heading")}
+}
+
+// emojiHeadingRule flags headings with symbols or emoji but no words, which
+// screen readers announce by the emoji's name, if at all.
+func emojiHeadingRule(n ast.Node, source []byte, lineOffsets []int) []LintWarning {
+	heading := n.(*ast.Heading)
+	text := strings.TrimSpace(plainText(heading, source))
+	offset, ok := headingOffset(heading)
+	if !ok || text == "" || strings.IndexFunc(text, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+		return nil
+	}
+	return []LintWarning{warningAt(offset, source, lineOffsets, "Heading has only emoji or symbols; add words")}
+}
+
+// ----- Helpers -----
+
+func looksLikeURL(text string) bool {
+	lower := strings.ToLower(text)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "www.")
+}
+
+// inlineOffset is where an inline node starts. Images without alt text and
+// autolinks have no text segment of their own, so they start where the
+// text before them stops.
+func inlineOffset(n ast.Node) (int, bool) {
+	if _, isAutoLink := n.(*ast.AutoLink); !isAutoLink {
+		if offset, ok := firstOffset(n); ok {
+			return offset, true
+		}
+	}
+	if prev, ok := n.PreviousSibling().(*ast.Text); ok {
+		return prev.Segment.Stop, true
+	}
+	if n.PreviousSibling() == nil && n.Parent() != nil && n.Parent().Type() == ast.TypeBlock {
+		return firstOffset(n.Parent())
+	}
+	return nodeOffset(n)
+}
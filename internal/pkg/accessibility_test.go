@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestLint_AccessibilityRules(t *testing.T) {
+	type position struct{ Line, Column int }
+
+	tests := []struct {
+		name     string
+		rule     string
+		markdown string
+		expected []position
+	}{
+		{"image without alt", "a11y-image-alt", "See ![](diagram.png) below.\n", []position{{1, 5}}},
+		{"image starting a paragraph", "a11y-image-alt", "![](diagram.png)\n", []position{{1, 1}}},
+		{"image with alt", "a11y-image-alt", "See ![Sign-up flow](diagram.png).\n", nil},
+		{"click here", "a11y-link-text", "To sign up, [click here](https://example.com).\n", []position{{1, 14}}},
+		{"read more", "a11y-link-text", "[Read more…](https://example.com)\n", []position{{1, 2}}},
+		{"descriptive link", "a11y-link-text", "Read the [sign-up guide](https://example.com).\n", nil},
+		{"link text is the URL", "a11y-bare-url", "Go to [https://example.com](https://example.com).\n", []position{{1, 8}}},
+		{"autolink", "a11y-bare-url", "Go to <https://example.com>.\n", []position{{1, 7}}},
+		{"bare www link", "a11y-bare-url", "Go to www.example.com now.\n", []position{{1, 7}}},
+		{"email autolink", "a11y-bare-url", "Mail <team@example.com>.\n", nil},
+		{"empty header row", "a11y-table-header", "Intro\n\n|  |  |\n| --- | --- |\n| a | b |\n", []position{{3, 1}}},
+		{"header row", "a11y-table-header", "| Owner | Task |\n| --- | --- |\n| a | b |\n", nil},
+		{"emoji heading", "a11y-emoji-heading", "# Title\n\n## 🚀🚀\n\nText.\n", []position{{3, 4}}},
+		{"emoji with words", "a11y-emoji-heading", "## 🚀 Launch\n\nText.\n", nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []position
+			for _, w := range lintRule(t, tc.rule, tc.markdown) {
+				assert.Equal(t, tc.rule, w.Rule)
+				got = append(got, position{w.Line, w.Column})
+			}
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestLint_AccessibilityMessages(t *testing.T) {
+	warnings := lintRule(t, "a11y-link-text", "[Click here](https://example.com)\n")
+	require.Len(t, warnings, 1)
+	assert.Equal(t, `Link text "Click here" does not say where the link goes`, warnings[0].Message)
+	assert.Equal(t, SeverityWarning, warnings[0].Severity)
+
+	warnings = lintRule(t, "a11y-bare-url", "<https://example.com>\n")
+	require.Len(t, warnings, 1)
+	assert.Equal(t, SeverityInfo, warnings[0].Severity)
+}
@@ -0,0 +1,152 @@
+package utils
+
+import (
+	"fmt"
+	"github.com/yuin/goldmark/ast"
+	"golang.org/x/net/html"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// ----- Platform Compatibility -----
+
+// PlatformCapabilities is what a destination platform's mapper carries over
+// from Markdown. Each platform declares its own, from its mapper, so the
+// platform-compatibility rule can warn about the rest before staging.
+type PlatformCapabilities struct {
+	Name string // as authors know it, e.g. "Notion"
+	// Blocks are the block node types the mapper maps, including those a
+	// mapper reads itself, such as table rows. Any other block is dropped
+	// along with its content.
+	Blocks map[reflect.Type]bool
+	// MaxHeadingLevel is the deepest heading the platform has. Deeper
+	// headings become headings of that level. Zero means no limit.
+	MaxHeadingLevel int
+	// HTMLTags are the tags carried over from HTML blocks, and
+	// InlineHTMLTags those carried over from HTML inside a paragraph. Other
+	// tags are stripped, keeping their text, as are HTML comments.
+	HTMLTags       map[string]bool
+	InlineHTMLTags map[string]bool
+	Footnotes      bool // whether [^1] references and their notes become footnotes
+}
+
+// BlockTypes returns the types of nodes as a set, for Blocks.
+func BlockTypes(nodes ...ast.Node) map[reflect.Type]bool {
+	types := make(map[reflect.Type]bool, len(nodes))
+	for _, n := range nodes {
+		types[reflect.TypeOf(n)] = true
+	}
+	return types
+}
+
+// footnoteRe matches footnote references and the label of a footnote's
+// note, which Markdown without footnotes leaves as plain text.
+var footnoteRe = regexp.MustCompile(`\[\^[^\]\s]+\]`)
+
+// compatibilityRule checks the whole document against the destination
+// platform's capabilities. It walks the document the way a mapper does: a
+// block without a mapper is reported once and its content is not checked.
+func compatibilityRule(opts LintOptions) LintCheck {
+	platform := opts.Platform
+	return func(n ast.Node, source []byte, lineOffsets []int) []LintWarning {
+		if platform == nil {
+			return nil
+		}
+		var warnings []LintWarning
+		report := func(offset int, format string, args ...any) {
+			warnings = append(warnings, warningAt(offset, source, lineOffsets, fmt.Sprintf(format, args...)))
+		}
+
+		_ = ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+			if !entering {
+				return ast.WalkContinue, nil
+			}
+			if c.Type() == ast.TypeBlock && !platform.Blocks[reflect.TypeOf(c)] {
+				if offset, ok := nodeOffset(c); ok {
+					report(offset, "%s has no equivalent for %s blocks; it is left out of the draft", platform.Name, c.Kind())
+				}
+				return ast.WalkSkipChildren, nil
+			}
+
+			switch v := c.(type) {
+			case *ast.Heading:
+				if platform.MaxHeadingLevel > 0 && v.Level > platform.MaxHeadingLevel {
+					if offset, ok := headingOffset(v); ok {
+						report(offset, "%s has no h%d; this heading becomes h%d", platform.Name, v.Level, platform.MaxHeadingLevel)
+					}
+				}
+			case *ast.HTMLBlock:
+				if v.Lines().Len() > 0 {
+					for _, problem := range unsupportedHTML(htmlBlockText(v, source), platform.HTMLTags) {
+						report(v.Lines().At(0).Start, "%s strips %s", platform.Name, problem)
+					}
+				}
+			case *ast.RawHTML:
+				// A closing tag was reported with its opening tag
+				raw := string(v.Segments.Value(source))
+				if offset, ok := firstOffset(v); ok && !strings.HasPrefix(raw, "</") {
+					for _, problem := range unsupportedHTML(raw, platform.InlineHTMLTags) {
+						report(offset, "%s strips %s", platform.Name, problem)
+					}
+				}
+			case *ast.Text:
+				if platform.Footnotes {
+					break
+				}
+				start, stop, ok := textRun(v, source)
+				if !ok {
+					break
+				}
+				for _, loc := range footnoteRe.FindAllIndex(source[start:stop], -1) {
+					report(start+loc[0], "%s has no footnotes; %s stays as plain text",
+						platform.Name, source[start+loc[0]:start+loc[1]])
+				}
+			}
+			return ast.WalkContinue, nil
+		})
+		return warnings
+	}
+}
+
+// unsupportedHTML describes the tags in raw that are not in supported, and
+// any comments, once each in the order they first appear.
+func unsupportedHTML(raw string, supported map[string]bool) []string {
+	var problems []string
+	seen := make(map[string]bool)
+	add := func(problem string) {
+		if !seen[problem] {
+			seen[problem] = true
+			problems = append(problems, problem)
+		}
+	}
+
+	z := html.NewTokenizer(strings.NewReader(raw))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return problems
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			if tag := strings.ToLower(string(name)); !supported[tag] {
+				add(fmt.Sprintf("<%s> tags", tag))
+			}
+		case html.CommentToken:
+			add("HTML comments")
+		}
+	}
+}
+
+func htmlBlockText(block *ast.HTMLBlock, source []byte) string {
+	var sb strings.Builder
+	lines := block.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		sb.Write(segment.Value(source))
+	}
+	if block.HasClosure() {
+		closure := block.ClosureLine
+		sb.Write(closure.Value(source))
+	}
+	return sb.String()
+}
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yuin/goldmark/ast"
+	"testing"
+)
+
+// testPlatform maps paragraphs, headings up to h3, lists and bold HTML.
+var testPlatform = PlatformCapabilities{
+	Name:            "Pages",
+	Blocks:          BlockTypes(&ast.Document{}, &ast.Paragraph{}, &ast.Heading{}, &ast.List{}, &ast.ListItem{}, &ast.TextBlock{}, &ast.HTMLBlock{}),
+	MaxHeadingLevel: 3,
+	HTMLTags:        map[string]bool{"p": true, "b": true},
+	InlineHTMLTags:  map[string]bool{"b": true},
+}
+
+// lintPlatform lints markdown against platform with only the
+// platform-compatibility rule on.
+func lintPlatform(t *testing.T, platform *PlatformCapabilities, markdown string) []LintWarning {
+	rules := LintRuleSet{}
+	for _, r := range lintRules {
+		rules[r.ID] = RuleOff
+	}
+	rules["platform-compatibility"] = RuleOn
+
+	doc, source, err := NewDefaultMarkdownParser().Parse(markdown)
+	require.NoError(t, err)
+	warnings, err := NewPetrelMarkdownLinter().Lint(doc, source, LintOptions{Rules: rules, Platform: platform})
+	require.NoError(t, err)
+	return warnings
+}
+
+func TestLint_PlatformCompatibility(t *testing.T) {
+	type finding struct {
+		Line, Column int
+		Message      string
+	}
+
+	tests := []struct {
+		name     string
+		markdown string
+		expected []finding
+	}{
+		{"supported", "# Plan\n\n- Ship <b>it</b>.\n", nil},
+		{"deep heading", "# Plan\n\n#### Risks\n", []finding{{3, 6, "Pages has no h4; this heading becomes h3"}}},
+		{"unmapped block", "Intro\n\n> Quoted **text**\n> #### Deep\n", []finding{
+			{3, 3, "Pages has no equivalent for Blockquote blocks; it is left out of the draft"},
+		}},
+		{"unmapped nested block", "- one\n\n  ```go\n  x := 1\n  ```\n", []finding{
+			{3, 6, "Pages has no equivalent for FencedCodeBlock blocks; it is left out of the draft"},
+		}},
+		{"html block", "<p>\n<span>Hi</span> <b>there</b><span>!</span>\n<!-- todo -->\n</p>\n", []finding{
+			{1, 1, "Pages strips <span> tags"},
+			{1, 1, "Pages strips HTML comments"},
+		}},
+		{"inline html", "Say <b>hi</b> <kbd>Ctrl</kbd>.\n", []finding{
+			{1, 15, "Pages strips <kbd> tags"},
+		}},
+		{"footnotes", "Ship it.[^1] Then rest[^note].\n\n[^1]: Not before Friday.\n", []finding{
+			{1, 9, "Pages has no footnotes; [^1] stays as plain text"},
+			{1, 23, "Pages has no footnotes; [^note] stays as plain text"},
+			{3, 1, "Pages has no footnotes; [^1] stays as plain text"},
+		}},
+		{"footnote-like code", "Use `a[^1]` in regexes.\n", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []finding
+			for _, w := range lintPlatform(t, &testPlatform, tt.markdown) {
+				assert.Equal(t, "platform-compatibility", w.Rule)
+				assert.Equal(t, SeverityWarning, w.Severity)
+				got = append(got, finding{w.Line, w.Column, w.Message})
+			}
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestLint_PlatformCompatibilityOptional(t *testing.T) {
+	markdown := "#### Risks\n\n<kbd>x</kbd>[^1]\n\n> Quote\n"
+	assert.Empty(t, lintPlatform(t, nil, markdown))
+
+	withFootnotes := testPlatform
+	withFootnotes.Footnotes = true
+	assert.Len(t, lintPlatform(t, &withFootnotes, markdown), 3)
+	assert.Len(t, lintPlatform(t, &testPlatform, markdown), 4)
+
+	unlimited := testPlatform
+	unlimited.MaxHeadingLevel = 0
+	assert.Len(t, lintPlatform(t, &unlimited, markdown), 3)
+}
@@ -0,0 +1,224 @@
+package utils
+
+import (
+	"github.com/yuin/goldmark/ast"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ----- Fix Types -----
+
+// LintFix is a machine-applicable fix for a finding: replace the source
+// each edit covers with its NewText. Edits never overlap.
+type LintFix struct {
+	Description string     `json:"Description"`
+	Edits       []TextEdit `json:"Edits"`
+}
+
+// TextEdit replaces the source from Line:Column up to, not including,
+// EndLine:EndColumn. Start and End are the same range as byte offsets.
+// An insertion has an empty range.
+type TextEdit struct {
+	Line      int    `json:"Line"`
+	Column    int    `json:"Column"`
+	EndLine   int    `json:"EndLine"`
+	EndColumn int    `json:"EndColumn"`
+	Start     int    `json:"Start"`
+	End       int    `json:"End"`
+	NewText   string `json:"NewText"`
+}
+
+func newFix(description string, edits ...TextEdit) *LintFix {
+	if len(edits) == 0 {
+		return nil
+	}
+	return &LintFix{Description: description, Edits: edits}
+}
+
+func newTextEdit(start, end int, newText string, source []byte, lineOffsets []int) TextEdit {
+	line := getLine(start, lineOffsets)
+	endLine := getLine(end, lineOffsets)
+	return TextEdit{
+		Line:      line,
+		Column:    getColumn(start, line, source, lineOffsets),
+		EndLine:   endLine,
+		EndColumn: getColumn(end, endLine, source, lineOffsets),
+		Start:     start,
+		End:       end,
+		NewText:   newText,
+	}
+}
+
+// ApplyFixes applies the fixes of warnings to source and reports how many
+// it applied. A fix that overlaps one applied before it is skipped whole;
+// running the linter again on the result picks it up.
+func ApplyFixes(source []byte, warnings []LintWarning) ([]byte, int) {
+	var edits []TextEdit
+	applied := 0
+	for _, w := range warnings {
+		if w.Fix == nil || overlapsAny(w.Fix.Edits, edits) {
+			continue
+		}
+		edits = append(edits, w.Fix.Edits...)
+		applied++
+	}
+
+	// Stable, so insertions at the same offset keep the order of their findings
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].Start < edits[j].Start
+	})
+
+	var out strings.Builder
+	pos := 0
+	for _, edit := range edits {
+		out.Write(source[pos:edit.Start])
+		out.WriteString(edit.NewText)
+		pos = edit.End
+	}
+	out.Write(source[pos:])
+	return []byte(out.String()), applied
+}
+
+func overlapsAny(edits, accepted []TextEdit) bool {
+	for _, a := range edits {
+		for _, b := range accepted {
+			if a.Start < b.End && b.Start < a.End {
+				return true
+			}
+			// Replacing a range that an insertion falls inside
+			if (a.Start == a.End && b.Start < a.Start && a.Start < b.End) ||
+				(b.Start == b.End && a.Start < b.Start && b.Start < a.End) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ----- Fix Builders -----
+
+var trailingHeadingHashesRe = regexp.MustCompile(`\s*#+\s*$`)
+
+// startsParagraph reports whether t opens its paragraph, where a `#` would
+// have made a heading.
+func startsParagraph(t *ast.Text) bool {
+	if t.PreviousSibling() != nil {
+		return false
+	}
+	switch t.Parent().(type) {
+	case *ast.Paragraph, *ast.TextBlock:
+		return true
+	}
+	return false
+}
+
+func headingSpaceFix(t *ast.Text, source []byte, lineOffsets []int) *LintFix {
+	if !startsParagraph(t) {
+		return nil
+	}
+	txt := string(t.Segment.Value(source))
+	at := t.Segment.Start + len(txt) - len(strings.TrimLeft(txt, "#"))
+	return newFix("Add a space after the heading marker", newTextEdit(at, at, " ", source, lineOffsets))
+}
+
+// trailingHashFix removes the closing hashes from source[start:stop], a
+// line that would be a heading.
+func trailingHashFix(start, stop int, source []byte, lineOffsets []int) *LintFix {
+	loc := trailingHeadingHashesRe.FindIndex(source[start:stop])
+	if loc == nil {
+		return nil
+	}
+	return newFix("Remove the closing hashes",
+		newTextEdit(start+loc[0], start+loc[1], "", source, lineOffsets))
+}
+
+// spaceRuns returns the runs of two or more spaces that start in t, even
+// if they carry on into the next node, as [start, end) offsets. Spaces
+// before a line break are a hard break and are left out.
+func spaceRuns(t *ast.Text, source []byte) [][2]int {
+	var runs [][2]int
+	for i := t.Segment.Start; i < t.Segment.Stop; i++ {
+		if source[i] != ' ' || (i > 0 && source[i-1] == ' ') {
+			continue
+		}
+		end := i
+		for end < len(source) && source[end] == ' ' {
+			end++
+		}
+		if end-i < 2 || end == len(source) || source[end] == '\n' || source[end] == '\r' {
+			continue
+		}
+		runs = append(runs, [2]int{i, end})
+	}
+	return runs
+}
+
+func multipleSpacesFix(t *ast.Text, source []byte, lineOffsets []int) *LintFix {
+	var edits []TextEdit
+	for _, run := range spaceRuns(t, source) {
+		edits = append(edits, newTextEdit(run[0], run[1], " ", source, lineOffsets))
+	}
+	return newFix("Collapse repeated spaces", edits...)
+}
+
+// emphasisFix closes the last run of `*` in t at the end of its line. Runs
+// followed by a space cannot open emphasis, as in "2 * 3", so get no fix.
+func emphasisFix(t *ast.Text, source []byte, lineOffsets []int) *LintFix {
+	txt := t.Segment.Value(source)
+	last := strings.LastIndexByte(string(txt), '*')
+	if last < 0 {
+		return nil
+	}
+	first := last
+	for first > 0 && txt[first-1] == '*' {
+		first--
+	}
+	after := t.Segment.Start + last + 1
+	if after >= len(source) || strings.ContainsRune(" \t\r\n", rune(source[after])) {
+		return nil
+	}
+
+	end := after
+	for end < len(source) && source[end] != '\n' {
+		end++
+	}
+	for end > after && strings.ContainsRune(" \t\r", rune(source[end-1])) {
+		end--
+	}
+	marker := string(txt[first : last+1])
+	return newFix("Close the "+marker+" emphasis", newTextEdit(end, end, marker, source, lineOffsets))
+}
+
+// tightListFix removes the blank lines between the items of list. Blank
+// lines inside an item are content and stay, so such a list stays loose.
+func tightListFix(list *ast.List, source []byte, lineOffsets []int) *LintFix {
+	var edits []TextEdit
+	for item := list.FirstChild(); item != nil; item = item.NextSibling() {
+		if item.PreviousSibling() == nil || !item.HasBlankPreviousLines() {
+			continue
+		}
+		offset, ok := nodeOffset(item)
+		if !ok {
+			continue
+		}
+		line := getLine(offset, lineOffsets)
+		start := line
+		for start > 1 && isBlankLine(start-1, source, lineOffsets) {
+			start--
+		}
+		if start < line {
+			edits = append(edits, newTextEdit(lineOffsets[start-1], lineOffsets[line-1], "", source, lineOffsets))
+		}
+	}
+	return newFix("Remove the blank lines between list items", edits...)
+}
+
+func isBlankLine(line int, source []byte, lineOffsets []int) bool {
+	start := lineOffsets[line-1]
+	end := len(source)
+	if line < len(lineOffsets) {
+		end = lineOffsets[line]
+	}
+	return strings.TrimSpace(string(source[start:end])) == ""
+}
@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func lintMarkdown(t *testing.T, markdown string) ([]LintWarning, []byte) {
+	doc, source, err := NewDefaultMarkdownParser().Parse(markdown)
+	require.NoError(t, err)
+	warnings, err := NewPetrelMarkdownLinter().Lint(doc, source, LintOptions{})
+	require.NoError(t, err)
+	return warnings, source
+}
+
+func TestApplyFixes(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		fixed    string
+		applied  int
+	}{
+		{
+			name:     "heading markers",
+			markdown: "#Overview#\n\nBody\n",
+			fixed:    "# Overview\n\nBody\n",
+			applied:  2,
+		},
+		{
+			name:     "spaced closing hashes",
+			markdown: "#Heading ##\n\nBody\n",
+			fixed:    "# Heading\n\nBody\n",
+			applied:  2,
+		},
+		{
+			name:     "double spaces across nodes, hard break kept",
+			markdown: "Two  spaces  here  \nnext\n",
+			fixed:    "Two spaces here  \nnext\n",
+			applied:  1,
+		},
+		{
+			name:     "dangling emphasis",
+			markdown: "This is *important and **bold.\n",
+			fixed:    "This is *important and **bold.***\n",
+			applied:  2,
+		},
+		{
+			name:     "loose list",
+			markdown: "- one\n\n- two\n\n\n- three\n",
+			fixed:    "- one\n- two\n- three\n",
+			applied:  1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			warnings, source := lintMarkdown(t, tc.markdown)
+			fixed, applied := ApplyFixes(source, warnings)
+			assert.Equal(t, tc.fixed, string(fixed))
+			assert.Equal(t, tc.applied, applied)
+
+			// Fixed Markdown has nothing left to fix
+			again, _ := lintMarkdown(t, string(fixed))
+			for _, w := range again {
+				assert.Nil(t, w.Fix, "%s still fixable on line %d", w.Rule, w.Line)
+			}
+		})
+	}
+}
+
+func TestLintFix_Positions(t *testing.T) {
+	warnings, _ := lintMarkdown(t, "Intro\n\n#Héading\n")
+	require.Len(t, warnings, 1)
+	assert.Equal(t, "heading-missing-space", warnings[0].Rule)
+	assert.Equal(t, &LintFix{
+		Description: "Add a space after the heading marker",
+		Edits:       []TextEdit{{Line: 3, Column: 2, EndLine: 3, EndColumn: 2, Start: 8, End: 8, NewText: " "}},
+	}, warnings[0].Fix)
+}
+
+func TestLintFix_NotOffered(t *testing.T) {
+	t.Run("multiplication is not emphasis", func(t *testing.T) {
+		warnings, _ := lintMarkdown(t, "Compute 2 * 3 today.\n")
+		require.Len(t, warnings, 1)
+		assert.Equal(t, "unclosed-emphasis", warnings[0].Rule)
+		assert.Nil(t, warnings[0].Fix)
+	})
+
+	t.Run("blank lines inside an item", func(t *testing.T) {
+		warnings, _ := lintMarkdown(t, "- one\n\n  more\n- two\n")
+		require.Len(t, warnings, 1)
+		assert.Equal(t, "loose-list", warnings[0].Rule)
+		assert.Nil(t, warnings[0].Fix)
+	})
+}
+
+func TestApplyFixes_SkipsOverlaps(t *testing.T) {
+	source := []byte("abcdef")
+	fix := func(start, end int, text string) LintWarning {
+		return LintWarning{Fix: &LintFix{Edits: []TextEdit{{Start: start, End: end, NewText: text}}}}
+	}
+
+	fixed, applied := ApplyFixes(source, []LintWarning{fix(1, 3, "X"), fix(2, 4, "Y"), fix(4, 4, "+"), {}})
+	assert.Equal(t, "aXd+ef", string(fixed))
+	assert.Equal(t, 2, applied)
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/yuin/goldmark/ast"
+	"gopkg.in/yaml.v3"
+	"regexp"
+	"time"
+)
+
+// FrontMatter is the YAML block that may open a Markdown document:
+//
+//	---
+//	title: Quarterly report
+//	tags: [finance, q3]
+//	destinations:
+//	  - platform: notion
+//	    workspace_id: abc123
+//	---
+type FrontMatter struct {
+	Title        string                   `yaml:"title"`
+	Tags         []string                 `yaml:"tags"`
+	Source       string                   `yaml:"source"`
+	Destinations []FrontMatterDestination `yaml:"destinations"`
+	PublishAt    *time.Time               `yaml:"publish_at"`
+}
+
+// FrontMatterDestination is a destination hint, with the same fields as a
+// draft request destination.
+type FrontMatterDestination struct {
+	Platform    string `yaml:"platform"`
+	WorkspaceID string `yaml:"workspace_id"`
+	Append      bool   `yaml:"append"`
+	PageID      string `yaml:"page_id"`
+}
+
+// frontMatterAttribute is the document attribute Parse stores front matter under.
+var frontMatterAttribute = []byte("petrel-front-matter")
+
+// splitFrontMatter finds a front matter block at the very start of source
+// and returns its YAML and the offset where the Markdown body starts.
+func splitFrontMatter(source []byte) ([]byte, int, bool) {
+	first, _, ok := cutLine(source)
+	if !ok || !isFenceLine(first, "---") {
+		return nil, 0, false
+	}
+
+	yamlStart := len(first) + 1
+	for pos := yamlStart; pos < len(source); {
+		line, _, _ := cutLine(source[pos:])
+		end := pos + len(line)
+		if isFenceLine(line, "---") || isFenceLine(line, "...") {
+			return source[yamlStart:pos], min(end+1, len(source)), true
+		}
+		pos = end + 1
+	}
+	// Without a closing line the opening `---` is just a thematic break
+	return nil, 0, false
+}
+
+func isFenceLine(line []byte, fence string) bool {
+	return string(bytes.TrimRight(line, " \t\r")) == fence
+}
+
+// cutLine splits off the first line of b, reporting whether it ended in a newline.
+func cutLine(b []byte) ([]byte, []byte, bool) {
+	if i := bytes.IndexByte(b, '\n'); i >= 0 {
+		return b[:i], b[i+1:], true
+	}
+	return b, nil, false
+}
+
+// frontMatterKeyRe matches a block that opens with a `key:` line and so was
+// clearly meant as front matter.
+var frontMatterKeyRe = regexp.MustCompile(`^\s*[A-Za-z_][\w-]*:`)
+
+// parseFrontMatter decodes a front matter block. ok is false when the block
+// is not a YAML mapping, e.g. a document that opens with a thematic break.
+func parseFrontMatter(raw []byte) (fm *FrontMatter, ok bool, err error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(raw, &node); err != nil {
+		if frontMatterKeyRe.Match(raw) {
+			return nil, true, fmt.Errorf("invalid front matter: %w", err)
+		}
+		return nil, false, nil
+	}
+	if len(node.Content) == 0 {
+		return &FrontMatter{}, true, nil
+	}
+	if node.Content[0].Kind != yaml.MappingNode {
+		return nil, false, nil
+	}
+
+	fm = &FrontMatter{}
+	if err := node.Decode(fm); err != nil {
+		return nil, true, fmt.Errorf("invalid front matter: %w", err)
+	}
+	return fm, true, nil
+}
+
+// GetFrontMatter returns the front matter Parse extracted from doc, if any.
+func GetFrontMatter(doc ast.Node) (*FrontMatter, bool) {
+	if doc == nil {
+		return nil, false
+	}
+	value, ok := doc.AttributeString(string(frontMatterAttribute))
+	if !ok {
+		return nil, false
+	}
+	fm, ok := value.(*FrontMatter)
+	return fm, ok
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yuin/goldmark/ast"
+	"testing"
+	"time"
+)
+
+func TestParseFrontMatter(t *testing.T) {
+	markdown := `---
+title: Quarterly report
+tags: [finance, q3]
+source: agent-7
+publish_at: 2025-07-01T09:00:00Z
+destinations:
+  - platform: notion
+    workspace_id: ws-1
+---
+# Results
+
+Revenue grew.
+`
+	doc, source, err := NewDefaultMarkdownParser().Parse(markdown)
+	require.NoError(t, err)
+
+	fm, ok := GetFrontMatter(doc)
+	require.True(t, ok)
+	assert.Equal(t, "Quarterly report", fm.Title)
+	assert.Equal(t, []string{"finance", "q3"}, fm.Tags)
+	assert.Equal(t, "agent-7", fm.Source)
+	require.NotNil(t, fm.PublishAt)
+	assert.Equal(t, time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC), *fm.PublishAt)
+	assert.Equal(t, []FrontMatterDestination{{Platform: "notion", WorkspaceID: "ws-1"}}, fm.Destinations)
+
+	// The front matter is not part of the AST, and lines still count from the top
+	first := doc.FirstChild()
+	require.IsType(t, &ast.Heading{}, first)
+	assert.Equal(t, 10, NodeLine(first, source))
+	assert.Equal(t, 2, doc.ChildCount())
+}
+
+func TestParseFrontMatter_NotFrontMatter(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+	}{
+		{"no front matter", "# Title\n\nText\n"},
+		{"unclosed fence", "---\ntitle: x\n\nText\n"},
+		{"thematic breaks around prose", "---\n\nJust some prose.\n\n---\n\nMore text.\n"},
+		{"fence not on first line", "Intro\n\n---\ntitle: x\n---\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, _, err := NewDefaultMarkdownParser().Parse(tt.markdown)
+			require.NoError(t, err)
+			_, ok := GetFrontMatter(doc)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestParseFrontMatter_Invalid(t *testing.T) {
+	_, _, err := NewDefaultMarkdownParser().Parse("---\ntitle: [unclosed\n---\nText\n")
+	assert.ErrorContains(t, err, "invalid front matter")
+
+	_, _, err = NewDefaultMarkdownParser().Parse("---\ntags: {a: b}\n---\nText\n")
+	assert.ErrorContains(t, err, "invalid front matter")
+}
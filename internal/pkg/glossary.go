@@ -0,0 +1,294 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/obi2na/petrel/config"
+	"github.com/yuin/goldmark/ast"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ----- Glossary -----
+
+// Glossary is a team's terminology. Terms match whole words and phrases in
+// any case, across line breaks.
+type Glossary struct {
+	Banned    []GlossaryTerm
+	Preferred []GlossaryTerm
+	Products  []GlossaryTerm // Term and Replacement are the proper spelling
+	Overused  []GlossaryTerm
+}
+
+// GlossaryTerm is a term and what to write instead. Replacement is empty
+// when there is nothing to suggest but rewording.
+type GlossaryTerm struct {
+	Term        string
+	Replacement string
+	pattern     *regexp.Regexp
+}
+
+// overusedPhrases are the phrases generated drafts lean on, checked for
+// every team.
+var overusedPhrases = []GlossaryTerm{
+	newGlossaryTerm("delve into", "explore"),
+	newGlossaryTerm("delves into", "explores"),
+	newGlossaryTerm("it's important to note that", ""),
+	newGlossaryTerm("it is important to note that", ""),
+	newGlossaryTerm("in today's fast-paced world", ""),
+	newGlossaryTerm("in the realm of", "in"),
+	newGlossaryTerm("a testament to", "evidence of"),
+	newGlossaryTerm("navigate the complexities of", "handle"),
+	newGlossaryTerm("unlock the power of", "use"),
+	newGlossaryTerm("embark on a journey", "start"),
+	newGlossaryTerm("rich tapestry", ""),
+	newGlossaryTerm("game-changer", ""),
+	newGlossaryTerm("seamlessly integrate", "integrate"),
+	newGlossaryTerm("leverage", "use"),
+	newGlossaryTerm("utilize", "use"),
+	newGlossaryTerm("in order to", "to"),
+}
+
+// builtinGlossary is the glossary every team starts from.
+var builtinGlossary = &Glossary{Overused: overusedPhrases}
+
+func newGlossaryTerm(term, replacement string) GlossaryTerm {
+	words := strings.Fields(term)
+	for i, word := range words {
+		word = regexp.QuoteMeta(word)
+		words[i] = strings.NewReplacer("'", "['’]", "’", "['’]").Replace(word)
+	}
+	return GlossaryTerm{
+		Term:        term,
+		Replacement: replacement,
+		pattern:     regexp.MustCompile(`(?i)` + strings.Join(words, `\s+`)),
+	}
+}
+
+// NewGlossary builds a glossary from config. Every term needs text, and
+// preferred terms need a replacement.
+func NewGlossary(cfg config.GlossaryConfig) (*Glossary, error) {
+	g := &Glossary{}
+	var err error
+	if g.Banned, err = glossaryTerms("banned", cfg.Banned, false); err != nil {
+		return nil, err
+	}
+	if g.Preferred, err = glossaryTerms("preferred", cfg.Preferred, true); err != nil {
+		return nil, err
+	}
+	if g.Overused, err = glossaryTerms("overused", cfg.Overused, false); err != nil {
+		return nil, err
+	}
+	for _, product := range cfg.Products {
+		if strings.TrimSpace(product) == "" {
+			return nil, fmt.Errorf("%w: glossary products: empty name", ErrInvalidLintRules)
+		}
+		g.Products = append(g.Products, newGlossaryTerm(product, strings.TrimSpace(product)))
+	}
+	return g, nil
+}
+
+func glossaryTerms(list string, terms []config.GlossaryTermConfig, needReplacement bool) ([]GlossaryTerm, error) {
+	var out []GlossaryTerm
+	for _, t := range terms {
+		if strings.TrimSpace(t.Term) == "" {
+			return nil, fmt.Errorf("%w: glossary %s: empty term", ErrInvalidLintRules, list)
+		}
+		if needReplacement && strings.TrimSpace(t.Replacement) == "" {
+			return nil, fmt.Errorf("%w: glossary %s: %q has no replacement", ErrInvalidLintRules, list, t.Term)
+		}
+		out = append(out, newGlossaryTerm(strings.TrimSpace(t.Term), strings.TrimSpace(t.Replacement)))
+	}
+	return out, nil
+}
+
+// With returns g with the terms of other added. A term in both keeps
+// other's replacement. Neither glossary is modified.
+func (g *Glossary) With(other *Glossary) *Glossary {
+	if other == nil {
+		return g
+	}
+	if g == nil {
+		return other
+	}
+	return &Glossary{
+		Banned:    mergeTerms(g.Banned, other.Banned),
+		Preferred: mergeTerms(g.Preferred, other.Preferred),
+		Products:  mergeTerms(g.Products, other.Products),
+		Overused:  mergeTerms(g.Overused, other.Overused),
+	}
+}
+
+func mergeTerms(base, overrides []GlossaryTerm) []GlossaryTerm {
+	merged := make([]GlossaryTerm, 0, len(base)+len(overrides))
+	for _, t := range base {
+		if !containsTerm(overrides, t.Term) {
+			merged = append(merged, t)
+		}
+	}
+	return append(merged, overrides...)
+}
+
+func containsTerm(terms []GlossaryTerm, term string) bool {
+	for _, t := range terms {
+		if strings.EqualFold(t.Term, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// ----- Glossary Rules -----
+
+// termReplacement is how a glossary rule offers a term's replacement.
+type termReplacement int
+
+const (
+	// suggestReplacement only suggests it. Style advice such as "leverage"
+	// for "use" can change the meaning, so it is never applied unasked.
+	suggestReplacement termReplacement = iota
+	// fixReplacement also offers it as a fix, following the case of a
+	// capitalized match. For the team's exact term pairs.
+	fixReplacement
+	// fixExactCase offers it as a fix as written, for product names.
+	fixExactCase
+)
+
+// glossaryRule binds a rule to the terms list picks from the team's
+// glossary. message describes a match, given the text found, or returns ""
+// when it needs no change. Every replacement is suggested; replace says
+// whether it is also a fix.
+func glossaryRule(list func(g *Glossary) []GlossaryTerm, message func(t GlossaryTerm, found string) string,
+	replace termReplacement) func(LintOptions) LintCheck {
+	return func(opts LintOptions) LintCheck {
+		terms := list(builtinGlossary.With(opts.Glossary))
+		return func(n ast.Node, source []byte, lineOffsets []int) []LintWarning {
+			start, stop, ok := textRun(n.(*ast.Text), source)
+			if !ok || len(terms) == 0 {
+				return nil
+			}
+			var warnings []LintWarning
+			for _, term := range terms {
+				for _, loc := range term.pattern.FindAllIndex(source[start:stop], -1) {
+					from, to := start+loc[0], start+loc[1]
+					found := string(source[from:to])
+					msg := message(term, found)
+					if msg == "" || !wholeWord(source, from, to) {
+						continue
+					}
+					w := warningAt(from, source, lineOffsets, msg)
+					if term.Replacement != "" {
+						replacement := term.Replacement
+						if replace != fixExactCase {
+							replacement = matchCase(found, replacement)
+						}
+						w.Suggestions = []string{replacement}
+						if replace != suggestReplacement {
+							w.Fix = newFix(fmt.Sprintf("Replace with %q", replacement),
+								newTextEdit(from, to, replacement, source, lineOffsets))
+						}
+					}
+					warnings = append(warnings, w)
+				}
+			}
+			sort.SliceStable(warnings, func(i, j int) bool {
+				return warnings[i].Line < warnings[j].Line ||
+					(warnings[i].Line == warnings[j].Line && warnings[i].Column < warnings[j].Column)
+			})
+			return warnings
+		}
+	}
+}
+
+func bannedTermMessage(t GlossaryTerm, found string) string {
+	if t.Replacement == "" {
+		return fmt.Sprintf("Avoid %q", found)
+	}
+	return fmt.Sprintf("Avoid %q; use %q", found, t.Replacement)
+}
+
+func preferredTermMessage(t GlossaryTerm, found string) string {
+	return fmt.Sprintf("Use %q instead of %q", t.Replacement, found)
+}
+
+func productNameMessage(t GlossaryTerm, found string) string {
+	if found == t.Replacement {
+		return ""
+	}
+	return fmt.Sprintf("Write %q, not %q", t.Replacement, found)
+}
+
+func overusedPhraseMessage(t GlossaryTerm, found string) string {
+	if t.Replacement == "" {
+		return fmt.Sprintf("Overused phrase %q; reword or cut it", found)
+	}
+	return fmt.Sprintf("Overused phrase %q; consider %q", found, t.Replacement)
+}
+
+// ----- Helpers -----
+
+// textRun returns the source range of t and the text nodes that follow it
+// with only whitespace in between, so phrases split by line breaks or by
+// the inline parsers still match. Only the first node of a run reports
+// it, and text in code spans is skipped.
+func textRun(t *ast.Text, source []byte) (int, int, bool) {
+	if _, ok := t.Parent().(*ast.CodeSpan); ok {
+		return 0, 0, false
+	}
+	if prev, ok := t.PreviousSibling().(*ast.Text); ok && joinedText(prev, t, source) {
+		return 0, 0, false
+	}
+	start, stop := t.Segment.Start, t.Segment.Stop
+	for cur := t; ; {
+		next, ok := cur.NextSibling().(*ast.Text)
+		if !ok || !joinedText(cur, next, source) {
+			break
+		}
+		stop, cur = next.Segment.Stop, next
+	}
+	return start, stop, true
+}
+
+func joinedText(a, b *ast.Text, source []byte) bool {
+	return a.Segment.Stop <= b.Segment.Start && len(bytes.TrimSpace(source[a.Segment.Stop:b.Segment.Start])) == 0
+}
+
+// wholeWord reports whether source[start:end] is not part of a longer
+// word, file name, address or URL.
+func wholeWord(source []byte, start, end int) bool {
+	if start > 0 {
+		r, _ := utf8.DecodeLastRune(source[:start])
+		if isWordRune(r) || strings.ContainsRune("./@-", r) {
+			return false
+		}
+	}
+	if end < len(source) {
+		r, size := utf8.DecodeRune(source[end:])
+		if isWordRune(r) {
+			return false
+		}
+		if strings.ContainsRune("./@-", r) && end+size < len(source) {
+			if next, _ := utf8.DecodeRune(source[end+size:]); isWordRune(next) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// matchCase capitalizes replacement when found starts a sentence.
+func matchCase(found, replacement string) string {
+	f, _ := utf8.DecodeRuneInString(found)
+	r, size := utf8.DecodeRuneInString(replacement)
+	if !unicode.IsUpper(f) || !unicode.IsLower(r) {
+		return replacement
+	}
+	return string(unicode.ToUpper(r)) + replacement[size:]
+}
@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"github.com/obi2na/petrel/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+var testGlossary = config.GlossaryConfig{
+	Banned:    []config.GlossaryTermConfig{{Term: "simply"}, {Term: "whitelist", Replacement: "allowlist"}},
+	Preferred: []config.GlossaryTermConfig{{Term: "login", Replacement: "sign in"}, {Term: "e-mail", Replacement: "email"}},
+	Products:  []string{"Petrel", "GitHub", "iPhone"},
+}
+
+func lintGlossary(t *testing.T, cfg config.GlossaryConfig, markdown string) []LintWarning {
+	glossary, err := NewGlossary(cfg)
+	require.NoError(t, err)
+
+	rules := LintRuleSet{}
+	for _, r := range lintRules {
+		if !strings.HasPrefix(r.ID, "glossary-") {
+			rules[r.ID] = RuleOff
+		}
+	}
+	doc, source, err := NewDefaultMarkdownParser().Parse(markdown)
+	require.NoError(t, err)
+	warnings, err := NewPetrelMarkdownLinter().Lint(doc, source, LintOptions{Rules: rules, Glossary: glossary})
+	require.NoError(t, err)
+	return warnings
+}
+
+func TestLint_Glossary(t *testing.T) {
+	tests := []struct {
+		name        string
+		markdown    string
+		rule        string
+		line        int
+		column      int
+		message     string
+		replacement string // "" when none is suggested
+		fix         bool   // whether the replacement is also a fix
+	}{
+		{"banned", "Just simply run it.\n", "glossary-banned", 1, 6, `Avoid "simply"`, "", false},
+		{"banned with replacement", "Add it to the whitelist.\n", "glossary-banned", 1, 15, `Avoid "whitelist"; use "allowlist"`, "allowlist", true},
+		{"preferred", "Open the login page.\n", "glossary-preferred", 1, 10, `Use "sign in" instead of "login"`, "sign in", true},
+		{"preferred keeps sentence case", "Login first.\n", "glossary-preferred", 1, 1, `Use "sign in" instead of "Login"`, "Sign in", true},
+		{"hyphenated term", "Send an e-mail.\n", "glossary-preferred", 1, 9, `Use "email" instead of "e-mail"`, "email", true},
+		{"product name", "Push to Github.\n", "glossary-product-name", 1, 9, `Write "GitHub", not "Github"`, "GitHub", true},
+		{"product name keeps its case", "Tested on an IPHONE.\n", "glossary-product-name", 1, 14, `Write "iPhone", not "IPHONE"`, "iPhone", true},
+		{"built-in overused phrase", "We delve into the data.\n", "glossary-overused", 1, 4, `Overused phrase "delve into"; consider "explore"`, "explore", false},
+		{"phrase across lines", "It's important\nto note that it works.\n", "glossary-overused", 1, 1, `Overused phrase "It's important\nto note that"; reword or cut it`, "", false},
+		{"curly apostrophe", "It’s important to note that it works.\n", "glossary-overused", 1, 1, `Overused phrase "It’s important to note that"; reword or cut it`, "", false},
+		{"inside emphasis", "Read *the login guide*.\n", "glossary-preferred", 1, 11, `Use "sign in" instead of "login"`, "sign in", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			warnings := lintGlossary(t, testGlossary, tc.markdown)
+			require.Len(t, warnings, 1)
+			w := warnings[0]
+			assert.Equal(t, tc.rule, w.Rule)
+			assert.Equal(t, tc.line, w.Line)
+			assert.Equal(t, tc.column, w.Column)
+			assert.Equal(t, tc.message, w.Message)
+			if tc.replacement == "" {
+				assert.Empty(t, w.Suggestions)
+			} else {
+				assert.Equal(t, []string{tc.replacement}, w.Suggestions)
+			}
+			if !tc.fix {
+				assert.Nil(t, w.Fix)
+				return
+			}
+			require.NotNil(t, w.Fix)
+			assert.Equal(t, tc.replacement, w.Fix.Edits[0].NewText)
+		})
+	}
+}
+
+func TestLint_GlossaryIgnores(t *testing.T) {
+	for _, markdown := range []string{
+		"Petrel syncs with GitHub on your iPhone.\n",
+		"See github.com/obi2na/petrel for the loginService.\n",
+		"Run `login --simply` in a shell.\n",
+		"```\nlogin\n```\n",
+		"Write to petrel@example.com.\n",
+	} {
+		assert.Empty(t, lintGlossary(t, testGlossary, markdown), markdown)
+	}
+}
+
+func TestLint_GlossaryFixes(t *testing.T) {
+	markdown := "Login to Github in order to leverage the API.\n"
+	doc, source, err := NewDefaultMarkdownParser().Parse(markdown)
+	require.NoError(t, err)
+	glossary, err := NewGlossary(testGlossary)
+	require.NoError(t, err)
+	warnings, err := NewPetrelMarkdownLinter().Lint(doc, source, LintOptions{Glossary: glossary})
+	require.NoError(t, err)
+
+	// Overused phrases are only suggested; "leverage" may be a noun
+	fixed, applied := ApplyFixes(source, warnings)
+	assert.Equal(t, 2, applied)
+	assert.Equal(t, "Sign in to GitHub in order to leverage the API.\n", string(fixed))
+}
+
+func TestNewGlossary(t *testing.T) {
+	_, err := NewGlossary(config.GlossaryConfig{Preferred: []config.GlossaryTermConfig{{Term: "login"}}})
+	assert.ErrorIs(t, err, ErrInvalidLintRules)
+	assert.ErrorContains(t, err, `"login" has no replacement`)
+
+	_, err = NewGlossary(config.GlossaryConfig{Banned: []config.GlossaryTermConfig{{Term: " "}}})
+	assert.ErrorIs(t, err, ErrInvalidLintRules)
+
+	_, err = NewGlossary(config.GlossaryConfig{Products: []string{""}})
+	assert.ErrorIs(t, err, ErrInvalidLintRules)
+}
+
+func TestLintPolicy_Glossary(t *testing.T) {
+	policy, err := NewLintPolicy(config.LintConfig{
+		Glossary: config.GlossaryConfig{Preferred: []config.GlossaryTermConfig{{Term: "login", Replacement: "sign in"}}},
+		Teams: map[string]config.LintRuleConfig{
+			"ws-brand": {Glossary: config.GlossaryConfig{
+				Preferred: []config.GlossaryTermConfig{{Term: "login", Replacement: "log in"}},
+				Products:  []string{"Petrel"},
+			}},
+		},
+	})
+	require.NoError(t, err)
+
+	g := policy.GlossaryFor("WS-BRAND", "user-1")
+	require.Len(t, g.Preferred, 1)
+	assert.Equal(t, "log in", g.Preferred[0].Replacement)
+	require.Len(t, g.Products, 1)
+
+	g = policy.GlossaryFor("ws-other", "user-1")
+	require.Len(t, g.Preferred, 1)
+	assert.Equal(t, "sign in", g.Preferred[0].Replacement)
+	assert.Empty(t, g.Products)
+
+	var none *LintPolicy
+	assert.Nil(t, none.GlossaryFor("ws-brand", "user-1"))
+
+	_, err = NewLintPolicy(config.LintConfig{Teams: map[string]config.LintRuleConfig{
+		"ws-brand": {Glossary: config.GlossaryConfig{Products: []string{" "}}},
+	}})
+	assert.ErrorIs(t, err, ErrInvalidLintRules)
+	assert.ErrorContains(t, err, "team ws-brand")
+}
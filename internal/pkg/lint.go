@@ -0,0 +1,550 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"github.com/obi2na/petrel/config"
+	"github.com/yuin/goldmark/ast"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"strings"
+)
+
+// ----- Severities -----
+
+// Severity grades a lint finding.
+type Severity string
+
+const (
+	SeverityInfo    Severity = "info"
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+var severityRank = map[Severity]int{
+	SeverityInfo:    1,
+	SeverityWarning: 2,
+	SeverityError:   3,
+}
+
+// ParseSeverity reads "info", "warning" or "error", in any case.
+func ParseSeverity(s string) (Severity, error) {
+	severity := Severity(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := severityRank[severity]; !ok {
+		return "", fmt.Errorf("unknown severity %q (want info, warning or error)", s)
+	}
+	return severity, nil
+}
+
+// ParseFailOn reads a fail_on threshold: a severity, or "none" (or empty)
+// for findings that never block staging, which it returns as "".
+func ParseFailOn(s string) (Severity, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "none":
+		return "", nil
+	}
+	return ParseSeverity(s)
+}
+
+// StricterFailOn returns whichever threshold blocks more findings. An
+// empty threshold blocks none.
+func StricterFailOn(a, b Severity) Severity {
+	if a == "" || (b != "" && severityRank[b] < severityRank[a]) {
+		return b
+	}
+	return a
+}
+
+// AtLeast reports whether s is as severe as threshold or more.
+func (s Severity) AtLeast(threshold Severity) bool {
+	return severityRank[s] >= severityRank[threshold]
+}
+
+// ----- Rules -----
+
+// LintRule is a single lint check. Its ID is stable so rule sets and
+// clients can refer to it.
+type LintRule struct {
+	ID          string
+	Severity    Severity // unless a rule set changes it
+	Description string
+	DefaultOff  bool       // only runs when a rule set turns it on
+	Nodes       []ast.Node // node types the rule checks
+	Check       LintCheck
+	// Bind builds the check from the lint options instead, for rules that
+	// depend on team data such as the glossary. It is called once per run.
+	Bind func(opts LintOptions) LintCheck
+}
+
+// LintCheck reports the findings for one node.
+type LintCheck func(n ast.Node, source []byte, lineOffsets []int) []LintWarning
+
+// lintRules is every rule PetrelMarkdownLinter runs. Never rename an ID;
+// teams refer to them in their rule sets.
+var lintRules = []LintRule{
+	{
+		ID:          "heading-depth",
+		Severity:    SeverityWarning,
+		Description: "Headings nested deeper than h3",
+		Nodes:       []ast.Node{&ast.Heading{}},
+		Check:       headingDepthRule,
+	},
+	{
+		ID:          "heading-skipped-level",
+		Severity:    SeverityWarning,
+		Description: "Headings more than one level below the heading before them",
+		Nodes:       []ast.Node{&ast.Heading{}},
+		Check:       skippedHeadingLevelRule,
+	},
+	{
+		ID:          "heading-duplicate",
+		Severity:    SeverityWarning,
+		Description: "Headings repeated within the same section",
+		Nodes:       []ast.Node{&ast.Heading{}},
+		Check:       duplicateHeadingRule,
+	},
+	{
+		ID:          "missing-title",
+		Severity:    SeverityWarning,
+		Description: "Documents that do not open with an h1 title",
+		DefaultOff:  true, // drafts usually carry their title in the request
+		Nodes:       []ast.Node{&ast.Document{}},
+		Check:       missingTitleRule,
+	},
+	{
+		ID:          "empty-section",
+		Severity:    SeverityWarning,
+		Description: "Headings with no content before the next heading",
+		Nodes:       []ast.Node{&ast.Heading{}},
+		Check:       emptySectionRule,
+	},
+	{
+		ID:          "long-paragraph",
+		Severity:    SeverityInfo,
+		Description: "Paragraphs over 150 words",
+		Nodes:       []ast.Node{&ast.Paragraph{}},
+		Check:       longParagraphRule,
+	},
+	{
+		ID:          "single-item-list",
+		Severity:    SeverityInfo,
+		Description: "Lists with only one item",
+		Nodes:       []ast.Node{&ast.List{}},
+		Check:       singleItemListRule,
+	},
+	{
+		ID:          "readability-grade",
+		Severity:    SeverityWarning,
+		Description: "Drafts above the team's reading grade level",
+		Nodes:       []ast.Node{&ast.Document{}},
+		Bind:        readabilityRule(gradeOverLimit),
+	},
+	{
+		ID:          "draft-length",
+		Severity:    SeverityWarning,
+		Description: "Drafts over the team's word limit",
+		Nodes:       []ast.Node{&ast.Document{}},
+		Bind:        readabilityRule(lengthOverLimit),
+	},
+	{
+		ID:          "loose-list",
+		Severity:    SeverityWarning,
+		Description: "Lists with blank lines between items",
+		Nodes:       []ast.Node{&ast.List{}},
+		Check:       looseListRule,
+	},
+	{
+		ID:          "todo",
+		Severity:    SeverityWarning,
+		Description: "Unfinished content marked TODO",
+		Nodes:       []ast.Node{&ast.Text{}},
+		Check:       textRule(containing("TODO"), "Contains unfinished content (TODO)"),
+	},
+	{
+		ID:          "glossary-banned",
+		Severity:    SeverityWarning,
+		Description: "Words the team glossary bans",
+		Nodes:       []ast.Node{&ast.Text{}},
+		Bind:        glossaryRule(func(g *Glossary) []GlossaryTerm { return g.Banned }, bannedTermMessage, fixReplacement),
+	},
+	{
+		ID:          "glossary-preferred",
+		Severity:    SeverityWarning,
+		Description: "Terms the team glossary has a preferred form for",
+		Nodes:       []ast.Node{&ast.Text{}},
+		Bind:        glossaryRule(func(g *Glossary) []GlossaryTerm { return g.Preferred }, preferredTermMessage, fixReplacement),
+	},
+	{
+		ID:          "glossary-product-name",
+		Severity:    SeverityWarning,
+		Description: "Product names not written in their proper case",
+		Nodes:       []ast.Node{&ast.Text{}},
+		Bind:        glossaryRule(func(g *Glossary) []GlossaryTerm { return g.Products }, productNameMessage, fixExactCase),
+	},
+	{
+		ID:          "glossary-overused",
+		Severity:    SeverityInfo,
+		Description: "Phrases generated drafts overuse, built in or from the glossary",
+		Nodes:       []ast.Node{&ast.Text{}},
+		Bind:        glossaryRule(func(g *Glossary) []GlossaryTerm { return g.Overused }, overusedPhraseMessage, suggestReplacement),
+	},
+	{
+		ID:          "spelling",
+		Severity:    SeverityInfo,
+		Description: "Words in neither the bundled word lists nor the team dictionary",
+		DefaultOff:  true, // teams turn it on once their dictionary covers their jargon
+		Nodes:       []ast.Node{&ast.Text{}},
+		Bind:        spellingRule,
+	},
+	{
+		ID:          "platform-compatibility",
+		Severity:    SeverityWarning,
+		Description: "Markdown the destination platform drops or degrades, such as deep headings, HTML and footnotes",
+		Nodes:       []ast.Node{&ast.Document{}},
+		Bind:        compatibilityRule,
+	},
+	{
+		ID:          "multiple-spaces",
+		Severity:    SeverityWarning,
+		Description: "Runs of spaces inside text",
+		Nodes:       []ast.Node{&ast.Text{}},
+		Check:       multipleSpacesRule,
+	},
+	{
+		ID:          "unclosed-emphasis",
+		Severity:    SeverityWarning,
+		Description: "Italic or bold markers without a closing marker",
+		Nodes:       []ast.Node{&ast.Text{}},
+		Check:       fixableTextRule(matching(unclosedEmphasis), "Unclosed italic/bold formatting", emphasisFix),
+	},
+	{
+		ID:          "heading-missing-space",
+		Severity:    SeverityWarning,
+		Description: "Heading hashes not followed by a space",
+		Nodes:       []ast.Node{&ast.Text{}},
+		Check:       fixableTextRule(matching(headingNoSpaceRe.MatchString), "Missing space after hash in heading", headingSpaceFix),
+	},
+	{
+		ID:          "heading-trailing-hash",
+		Severity:    SeverityWarning,
+		Description: "Closing hashes after heading text",
+		Nodes:       []ast.Node{&ast.Text{}},
+		Check:       trailingHashRule,
+	},
+	{
+		ID:          "malformed-link",
+		Severity:    SeverityWarning,
+		Description: "Links missing their closing parenthesis",
+		Nodes:       []ast.Node{&ast.Text{}},
+		Check:       textRule(matching(unclosedParenLinkRe.MatchString), "Malformed link (missing closing parenthesis)"),
+	},
+	{
+		ID:          "unmatched-math-delimiter",
+		Severity:    SeverityWarning,
+		Description: "$$ left in text because it opens no math block",
+		Nodes:       []ast.Node{&ast.Text{}},
+		Check:       textRule(containing("$$"), "Unmatched $$ math delimiter"),
+	},
+	{
+		ID:          "link-scheme",
+		Severity:    SeverityWarning,
+		Description: "Links whose destination is not an http(s) URL",
+		Nodes:       []ast.Node{&ast.Link{}},
+		Check:       linkSchemeRule,
+	},
+	{
+		ID:          "a11y-image-alt",
+		Severity:    SeverityWarning,
+		Description: "Images without alt text",
+		Nodes:       []ast.Node{&ast.Image{}},
+		Check:       imageAltRule,
+	},
+	{
+		ID:          "a11y-link-text",
+		Severity:    SeverityWarning,
+		Description: "Link text like \"click here\" that does not describe the destination",
+		Nodes:       []ast.Node{&ast.Link{}},
+		Check:       vagueLinkTextRule,
+	},
+	{
+		ID:          "a11y-bare-url",
+		Severity:    SeverityInfo,
+		Description: "Links whose text is the URL itself",
+		Nodes:       []ast.Node{&ast.Link{}, &ast.AutoLink{}},
+		Check:       bareURLRule,
+	},
+	{
+		ID:          "a11y-table-header",
+		Severity:    SeverityWarning,
+		Description: "Tables with an empty header row",
+		Nodes:       []ast.Node{&extast.Table{}},
+		Check:       tableHeaderRule,
+	},
+	{
+		ID:          "a11y-emoji-heading",
+		Severity:    SeverityWarning,
+		Description: "Headings made only of emoji or symbols",
+		Nodes:       []ast.Node{&ast.Heading{}},
+		Check:       emojiHeadingRule,
+	},
+	{
+		ID:          "math-unclosed-block",
+		Severity:    SeverityError,
+		Description: "Display math without a closing $$",
+		Nodes:       []ast.Node{&MathBlock{}},
+		Check:       unclosedMathBlockRule,
+	},
+	{
+		ID:          "math-empty",
+		Severity:    SeverityWarning,
+		Description: "Math with no expression",
+		Nodes:       []ast.Node{&MathBlock{}, &InlineMath{}},
+		Check:       mathRule(emptyMath, "Empty math expression"),
+	},
+	{
+		ID:          "math-unbalanced-braces",
+		Severity:    SeverityWarning,
+		Description: "Math expressions with unbalanced braces",
+		Nodes:       []ast.Node{&MathBlock{}, &InlineMath{}},
+		Check:       mathRule(unbalancedMathBraces, "Unbalanced braces in math expression"),
+	},
+	{
+		ID:          "math-mismatched-environment",
+		Severity:    SeverityWarning,
+		Description: "\\begin without a matching \\end, or the reverse",
+		Nodes:       []ast.Node{&MathBlock{}, &InlineMath{}},
+		Check:       mathRule(mismatchedMathEnvironments, "Mismatched \\begin/\\end environments in math expression"),
+	},
+}
+
+func lookupLintRule(id string) (LintRule, bool) {
+	for _, rule := range lintRules {
+		if rule.ID == id {
+			return rule, true
+		}
+	}
+	return LintRule{}, false
+}
+
+// ----- Rule Sets -----
+
+// Rule set values besides a severity.
+const (
+	RuleOff = "off"
+	RuleOn  = "on" // at the rule's default severity
+)
+
+var ErrInvalidLintRules = errors.New("invalid lint rules")
+
+// LintRuleSet changes rules by ID. A value is "off", "on" or the severity
+// to report the rule at, which also turns it on. Rules the set does not
+// mention keep their defaults.
+type LintRuleSet map[string]string
+
+// Validate checks that every ID names a rule and every value is valid.
+func (s LintRuleSet) Validate() error {
+	for id, value := range s {
+		if _, ok := lookupLintRule(strings.ToLower(id)); !ok {
+			return fmt.Errorf("%w: unknown rule %q", ErrInvalidLintRules, id)
+		}
+		switch strings.ToLower(value) {
+		case RuleOff, RuleOn:
+		default:
+			if _, err := ParseSeverity(value); err != nil {
+				return fmt.Errorf("%w: rule %q: %v", ErrInvalidLintRules, id, err)
+			}
+		}
+	}
+	return nil
+}
+
+// With returns s with overrides applied on top. Neither set is modified.
+func (s LintRuleSet) With(overrides LintRuleSet) LintRuleSet {
+	if len(overrides) == 0 {
+		return s
+	}
+	merged := make(LintRuleSet, len(s)+len(overrides))
+	for id, value := range s {
+		merged[strings.ToLower(id)] = value
+	}
+	for id, value := range overrides {
+		merged[strings.ToLower(id)] = value
+	}
+	return merged
+}
+
+// severity returns the severity rule reports at, or false when it is off.
+// The set must be valid.
+func (s LintRuleSet) severity(rule LintRule) (Severity, bool) {
+	value, ok := s[rule.ID]
+	if !ok {
+		for id, v := range s {
+			if strings.EqualFold(id, rule.ID) {
+				value, ok = v, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return rule.Severity, !rule.DefaultOff
+	}
+	switch strings.ToLower(value) {
+	case RuleOff:
+		return "", false
+	case RuleOn:
+		return rule.Severity, true
+	}
+	severity, _ := ParseSeverity(value)
+	return severity, true
+}
+
+// ----- Policy -----
+
+// LintPolicy holds the lint settings from config: the defaults, each
+// team's, keyed by Notion workspace ID, and each user's, keyed by user ID.
+type LintPolicy struct {
+	defaults lintSettings
+	teams    map[string]lintSettings
+	users    map[string]lintSettings
+}
+
+type lintSettings struct {
+	rules       LintRuleSet
+	failOn      *Severity // nil when not set at this level
+	glossary    *Glossary
+	dictionary  *Dictionary
+	readability ReadabilityLimits
+}
+
+// NewLintPolicy builds the policy from config, rejecting unknown rules
+// and severities.
+func NewLintPolicy(cfg config.LintConfig) (*LintPolicy, error) {
+	defaults, err := lintSettingsFromConfig(config.LintRuleConfig{
+		Rules:       cfg.Rules,
+		FailOn:      cfg.FailOn,
+		Glossary:    cfg.Glossary,
+		Dictionary:  cfg.Dictionary,
+		Readability: cfg.Readability,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &LintPolicy{
+		defaults: defaults,
+		teams:    make(map[string]lintSettings),
+		users:    make(map[string]lintSettings),
+	}
+	for workspace, team := range cfg.Teams {
+		settings, err := lintSettingsFromConfig(team)
+		if err != nil {
+			return nil, fmt.Errorf("lint rules for team %s: %w", workspace, err)
+		}
+		policy.teams[strings.ToLower(workspace)] = settings
+	}
+	for userID, user := range cfg.Users {
+		settings, err := lintSettingsFromConfig(user)
+		if err != nil {
+			return nil, fmt.Errorf("lint rules for user %s: %w", userID, err)
+		}
+		policy.users[strings.ToLower(userID)] = settings
+	}
+	return policy, nil
+}
+
+func lintSettingsFromConfig(cfg config.LintRuleConfig) (lintSettings, error) {
+	settings := lintSettings{rules: cfg.Rules}
+	if err := settings.rules.Validate(); err != nil {
+		return lintSettings{}, err
+	}
+	if cfg.FailOn != "" {
+		threshold, err := ParseFailOn(cfg.FailOn)
+		if err != nil {
+			return lintSettings{}, fmt.Errorf("%w: fail_on: %v", ErrInvalidLintRules, err)
+		}
+		settings.failOn = &threshold
+	}
+	glossary, err := NewGlossary(cfg.Glossary)
+	if err != nil {
+		return lintSettings{}, err
+	}
+	settings.glossary = glossary
+	dictionary, err := NewDictionary(cfg.Dictionary)
+	if err != nil {
+		return lintSettings{}, err
+	}
+	settings.dictionary = dictionary
+	if cfg.Readability.MaxGrade < 0 || cfg.Readability.MaxWords < 0 {
+		return lintSettings{}, fmt.Errorf("%w: readability limits cannot be negative", ErrInvalidLintRules)
+	}
+	settings.readability = ReadabilityLimits{MaxGrade: cfg.Readability.MaxGrade, MaxWords: cfg.Readability.MaxWords}
+	return settings, nil
+}
+
+// RulesFor returns the rule set for userID publishing to workspace. User
+// rules win over team rules, which win over the configured defaults.
+func (p *LintPolicy) RulesFor(workspace, userID string) LintRuleSet {
+	if p == nil {
+		return nil
+	}
+	return p.defaults.rules.
+		With(p.teams[strings.ToLower(workspace)].rules).
+		With(p.users[strings.ToLower(userID)].rules)
+}
+
+// FailOnFor returns the fail_on threshold for userID publishing to
+// workspace, with the same precedence as RulesFor. It is "" when lint
+// findings never block staging.
+func (p *LintPolicy) FailOnFor(workspace, userID string) Severity {
+	if p == nil {
+		return ""
+	}
+	var threshold Severity
+	for _, settings := range []lintSettings{p.defaults, p.teams[strings.ToLower(workspace)], p.users[strings.ToLower(userID)]} {
+		if settings.failOn != nil {
+			threshold = *settings.failOn
+		}
+	}
+	return threshold
+}
+
+// GlossaryFor returns the glossary for userID publishing to workspace: the
+// configured one with the team's and then the user's terms added. The
+// built-in overused phrases are added when linting.
+func (p *LintPolicy) GlossaryFor(workspace, userID string) *Glossary {
+	if p == nil {
+		return nil
+	}
+	return p.defaults.glossary.
+		With(p.teams[strings.ToLower(workspace)].glossary).
+		With(p.users[strings.ToLower(userID)].glossary)
+}
+
+// DictionaryFor returns the words userID publishing to workspace may use on
+// top of the bundled word lists: the configured ones, the team's and the
+// user's.
+func (p *LintPolicy) DictionaryFor(workspace, userID string) *Dictionary {
+	if p == nil {
+		return nil
+	}
+	return p.defaults.dictionary.
+		With(p.teams[strings.ToLower(workspace)].dictionary).
+		With(p.users[strings.ToLower(userID)].dictionary)
+}
+
+// ReadabilityFor returns the readability limits for userID publishing to
+// workspace. Each limit is taken from the most specific level that sets it.
+func (p *LintPolicy) ReadabilityFor(workspace, userID string) ReadabilityLimits {
+	if p == nil {
+		return ReadabilityLimits{}
+	}
+	var limits ReadabilityLimits
+	for _, settings := range []lintSettings{p.defaults, p.teams[strings.ToLower(workspace)], p.users[strings.ToLower(userID)]} {
+		if settings.readability.MaxGrade > 0 {
+			limits.MaxGrade = settings.readability.MaxGrade
+		}
+		if settings.readability.MaxWords > 0 {
+			limits.MaxWords = settings.readability.MaxWords
+		}
+	}
+	return limits
+}
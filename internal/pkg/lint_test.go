@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"github.com/obi2na/petrel/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+const lintSample = "# Release notes\n\nShip it é TODO: legal sign-off.\n\n- one\n\n- two\n"
+
+func lintSampleWith(t *testing.T, rules LintRuleSet) []LintWarning {
+	doc, source, err := NewDefaultMarkdownParser().Parse(lintSample)
+	require.NoError(t, err)
+	warnings, err := NewPetrelMarkdownLinter().Lint(doc, source, LintOptions{Rules: rules})
+	require.NoError(t, err)
+	return warnings
+}
+
+func TestLint_RuleIDsAndPositions(t *testing.T) {
+	warnings := lintSampleWith(t, nil)
+	require.Len(t, warnings, 2)
+	require.NotNil(t, warnings[1].Fix) // see fixes_test.go
+	warnings[1].Fix = nil
+
+	assert.Equal(t, []LintWarning{
+		{Rule: "todo", Severity: SeverityWarning, Line: 3, Column: 11, Message: "Contains unfinished content (TODO)"},
+		{Rule: "loose-list", Severity: SeverityWarning, Line: 5, Column: 3, Message: "Loose lists may reduce readability"},
+	}, warnings)
+}
+
+func TestLint_RuleSets(t *testing.T) {
+	t.Run("legal blocks TODOs", func(t *testing.T) {
+		warnings := lintSampleWith(t, LintRuleSet{"todo": "error"})
+		require.Len(t, warnings, 2)
+		assert.Equal(t, "todo", warnings[0].Rule)
+		assert.Equal(t, SeverityError, warnings[0].Severity)
+	})
+
+	t.Run("marketing ignores loose lists", func(t *testing.T) {
+		warnings := lintSampleWith(t, LintRuleSet{"loose-list": "off"})
+		require.Len(t, warnings, 1)
+		assert.Equal(t, "todo", warnings[0].Rule)
+	})
+
+	t.Run("on keeps the default severity", func(t *testing.T) {
+		warnings := lintSampleWith(t, LintRuleSet{"TODO": "On"})
+		require.Len(t, warnings, 2)
+		assert.Equal(t, SeverityWarning, warnings[0].Severity)
+	})
+}
+
+func TestLintRuleSet_Validate(t *testing.T) {
+	assert.NoError(t, LintRuleSet{"todo": "error", "loose-list": "off", "heading-depth": "on"}.Validate())
+
+	err := LintRuleSet{"no-such-rule": "error"}.Validate()
+	assert.ErrorIs(t, err, ErrInvalidLintRules)
+	assert.ErrorContains(t, err, `unknown rule "no-such-rule"`)
+
+	err = LintRuleSet{"todo": "fatal"}.Validate()
+	assert.ErrorIs(t, err, ErrInvalidLintRules)
+	assert.ErrorContains(t, err, `unknown severity "fatal"`)
+
+	doc, source, err := NewDefaultMarkdownParser().Parse(lintSample)
+	require.NoError(t, err)
+	_, err = NewPetrelMarkdownLinter().Lint(doc, source, LintOptions{Rules: LintRuleSet{"todo": "fatal"}})
+	assert.ErrorIs(t, err, ErrInvalidLintRules)
+}
+
+func TestLintPolicy(t *testing.T) {
+	policy, err := NewLintPolicy(config.LintConfig{
+		Rules: map[string]string{"heading-depth": "info", "todo": "warning"},
+		Teams: map[string]config.LintRuleConfig{
+			"WS-LEGAL":     {Rules: map[string]string{"todo": "error"}},
+			"ws-marketing": {Rules: map[string]string{"loose-list": "off"}},
+		},
+		Users: map[string]config.LintRuleConfig{
+			"user-1": {Rules: map[string]string{"todo": "off"}},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, LintRuleSet{"heading-depth": "info", "todo": "error"}, policy.RulesFor("ws-legal", "user-2"))
+	assert.Equal(t, LintRuleSet{"heading-depth": "info", "todo": "warning", "loose-list": "off"}, policy.RulesFor("ws-marketing", ""))
+	assert.Equal(t, LintRuleSet{"heading-depth": "info", "todo": "off"}, policy.RulesFor("ws-legal", "user-1"))
+
+	var none *LintPolicy
+	assert.Nil(t, none.RulesFor("ws-legal", "user-1"))
+
+	_, err = NewLintPolicy(config.LintConfig{
+		Teams: map[string]config.LintRuleConfig{"ws": {Rules: map[string]string{"todo": "loud"}}},
+	})
+	assert.ErrorContains(t, err, "lint rules for team ws")
+}
+
+func TestSeverity_AtLeast(t *testing.T) {
+	assert.True(t, SeverityError.AtLeast(SeverityWarning))
+	assert.True(t, SeverityWarning.AtLeast(SeverityWarning))
+	assert.False(t, SeverityInfo.AtLeast(SeverityWarning))
+}
+
+func TestLintPolicy_FailOn(t *testing.T) {
+	policy, err := NewLintPolicy(config.LintConfig{
+		FailOn: "error",
+		Teams: map[string]config.LintRuleConfig{
+			"ws-marketing": {FailOn: "none"},
+			"ws-legal":     {FailOn: "warning"},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, SeverityError, policy.FailOnFor("ws-other", ""))
+	assert.Equal(t, Severity(""), policy.FailOnFor("ws-marketing", ""))
+	assert.Equal(t, SeverityWarning, policy.FailOnFor("ws-legal", ""))
+
+	_, err = NewLintPolicy(config.LintConfig{FailOn: "sometimes"})
+	assert.ErrorIs(t, err, ErrInvalidLintRules)
+}
+
+func TestStricterFailOn(t *testing.T) {
+	assert.Equal(t, SeverityWarning, StricterFailOn(SeverityError, SeverityWarning))
+	assert.Equal(t, SeverityError, StricterFailOn("", SeverityError))
+	assert.Equal(t, SeverityInfo, StricterFailOn(SeverityInfo, ""))
+	assert.Equal(t, Severity(""), StricterFailOn("", ""))
+}
@@ -12,6 +12,7 @@ import (
 	"reflect"
 	"regexp"
 	"strings"
+	"unicode/utf8"
 )
 
 // ----- Interfaces and Types -----
@@ -24,38 +25,81 @@ type DefaultMarkdownParser struct {
 	engine goldmark.Markdown
 }
 
-func NewDefaultMarkdownParser() *DefaultMarkdownParser {
+// NewDefaultMarkdownParser returns a parser for GFM with Petrel's math and
+// reference syntax, plus any extra goldmark extensions.
+func NewDefaultMarkdownParser(extensions ...goldmark.Extender) *DefaultMarkdownParser {
 	return &DefaultMarkdownParser{
 		engine: goldmark.New(
-			goldmark.WithExtensions(extension.GFM),
+			goldmark.WithExtensions(append([]goldmark.Extender{extension.GFM, Math, References}, extensions...)...),
 			goldmark.WithParserOptions(parser.WithAutoHeadingID()),
 		),
 	}
 }
 
+// Parse parses markdown into a goldmark AST. A leading YAML front matter
+// block is left out of the AST and attached to the document instead; see
+// GetFrontMatter. Node positions still refer to the full source.
 func (p *DefaultMarkdownParser) Parse(markdown string) (ast.Node, []byte, error) {
 	source := []byte(markdown)
 	reader := text.NewReader(source)
+
+	var frontMatter *FrontMatter
+	if raw, bodyStart, ok := splitFrontMatter(source); ok {
+		fm, isFrontMatter, err := parseFrontMatter(raw)
+		if err != nil {
+			return nil, nil, err
+		}
+		if isFrontMatter {
+			frontMatter = fm
+			reader.Advance(bodyStart)
+		}
+	}
+
 	node := p.engine.Parser().Parse(reader)
 
 	if node == nil || node.ChildCount() == 0 {
 		return nil, nil, errors.New("invalid or empty markdown")
 	}
 
+	if frontMatter != nil {
+		node.SetAttribute(frontMatterAttribute, frontMatter)
+	}
+
 	return node, source, nil
 }
 
+// LintWarning is one lint finding. Rule is the ID of the rule that raised
+// it; Line and Column (in characters) are 1-based. Fix is set when the
+// finding can be fixed mechanically; see ApplyFixes. Suggestions are
+// replacements for the author to choose from, and are never applied
+// unless one is also the Fix.
 type LintWarning struct {
-	Line    int    `json:"Line"`
-	Message string `json:"Message"`
+	Rule        string   `json:"Rule,omitempty"`
+	Severity    Severity `json:"Severity,omitempty"`
+	Line        int      `json:"Line"`
+	Column      int      `json:"Column,omitempty"`
+	Message     string   `json:"Message"`
+	Fix         *LintFix `json:"Fix,omitempty"`
+	Suggestions []string `json:"Suggestions,omitempty"`
 }
 
 type MarkdownLinter interface {
-	Lint(doc ast.Node, source []byte) ([]LintWarning, error)
+	// Lint checks doc with the rules in opts.Rules, or the defaults when nil.
+	Lint(doc ast.Node, source []byte, opts LintOptions) ([]LintWarning, error)
+}
+
+// LintOptions is what a lint run needs beyond the document: the rule set
+// and the team data some rules check against.
+type LintOptions struct {
+	Rules       LintRuleSet
+	Glossary    *Glossary   // nil for the built-in terms only
+	Dictionary  *Dictionary // the team's words; nil for the bundled lists only
+	Readability ReadabilityLimits
+	Platform    *PlatformCapabilities // the destination to check against; nil to skip
 }
 
 type PetrelMarkdownLinter struct {
-	ruleMap map[reflect.Type]func(ast.Node, []byte, []int) []LintWarning
+	ruleMap map[reflect.Type][]LintRule
 }
 
 // ----- Global Regex -----
@@ -65,23 +109,27 @@ var (
 	trailingHashInHeadingRe = regexp.MustCompile(`^#{1,6}.*[^#]\s*#+$`)
 	unclosedParenLinkRe     = regexp.MustCompile(`$begin:math:display$[^$end:math:display$]+\]\([^)]+$`)
 	unclosedAsteriskRe      = regexp.MustCompile(`\*[^*]*$`)
+	mathBeginRe             = regexp.MustCompile(`\\begin\{[^}]*\}`)
+	mathEndRe               = regexp.MustCompile(`\\end\{[^}]*\}`)
 )
 
 // ----- Linter Core -----
 
 func NewPetrelMarkdownLinter() *PetrelMarkdownLinter {
 	l := &PetrelMarkdownLinter{
-		ruleMap: make(map[reflect.Type]func(ast.Node, []byte, []int) []LintWarning),
+		ruleMap: make(map[reflect.Type][]LintRule),
 	}
 	l.registerRules()
 	return l
 }
 
 func (l *PetrelMarkdownLinter) registerRules() {
-	l.ruleMap[reflect.TypeOf(&ast.Heading{})] = headingRules
-	l.ruleMap[reflect.TypeOf(&ast.List{})] = listRules
-	l.ruleMap[reflect.TypeOf(&ast.Text{})] = textRules
-	l.ruleMap[reflect.TypeOf(&ast.Link{})] = linkRules
+	for _, rule := range lintRules {
+		for _, node := range rule.Nodes {
+			t := reflect.TypeOf(node)
+			l.ruleMap[t] = append(l.ruleMap[t], rule)
+		}
+	}
 }
 
 func extractHeadings(doc ast.Node, source []byte) {
@@ -108,16 +156,36 @@ func extractHeadings(doc ast.Node, source []byte) {
 	}
 }
 
-func (l *PetrelMarkdownLinter) Lint(doc ast.Node, source []byte) ([]LintWarning, error) {
+func (l *PetrelMarkdownLinter) Lint(doc ast.Node, source []byte, opts LintOptions) ([]LintWarning, error) {
+	if err := opts.Rules.Validate(); err != nil {
+		return nil, err
+	}
+
 	var warnings []LintWarning
 	lineOffsets := buildLineOffsets(source)
+	bound := make(map[string]LintCheck)
 
 	err := ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
 		if !entering {
 			return ast.WalkContinue, nil
 		}
-		if ruleFn, ok := l.ruleMap[reflect.TypeOf(n)]; ok {
-			warnings = append(warnings, ruleFn(n, source, lineOffsets)...)
+		for _, rule := range l.ruleMap[reflect.TypeOf(n)] {
+			severity, ok := opts.Rules.severity(rule)
+			if !ok {
+				continue
+			}
+			check := rule.Check
+			if rule.Bind != nil {
+				if check, ok = bound[rule.ID]; !ok {
+					check = rule.Bind(opts)
+					bound[rule.ID] = check
+				}
+			}
+			for _, w := range check(n, source, lineOffsets) {
+				w.Rule = rule.ID
+				w.Severity = severity
+				warnings = append(warnings, w)
+			}
 		}
 		return ast.WalkContinue, nil
 	})
@@ -130,73 +198,167 @@ func (l *PetrelMarkdownLinter) Lint(doc ast.Node, source []byte) ([]LintWarning,
 
 // ----- Rule Implementations -----
 
-func headingRules(n ast.Node, source []byte, lineOffsets []int) []LintWarning {
-	var warnings []LintWarning
+func headingDepthRule(n ast.Node, source []byte, lineOffsets []int) []LintWarning {
 	heading := n.(*ast.Heading)
-
 	if heading.Level > 3 && heading.Lines().Len() > 0 {
-		line := getLine(heading.Lines().At(0).Start, lineOffsets)
-		warnings = append(warnings, LintWarning{
-			Line:    line,
-			Message: "Avoid using deeply nested headings (h4 or deeper)",
-		})
+		return []LintWarning{warningAt(heading.Lines().At(0).Start, source, lineOffsets,
+			"Avoid using deeply nested headings (h4 or deeper)")}
 	}
-
-	return warnings
+	return nil
 }
 
-func listRules(n ast.Node, _ []byte, lineOffsets []int) []LintWarning {
-	var warnings []LintWarning
+func looseListRule(n ast.Node, source []byte, lineOffsets []int) []LintWarning {
 	list := n.(*ast.List)
-	if !list.IsTight && list.Lines().Len() > 0 {
-		line := getLine(list.Lines().At(0).Start, lineOffsets)
-		warnings = append(warnings, LintWarning{
-			Line:    line,
-			Message: "Loose lists may reduce readability",
-		})
+	// Containers have no lines of their own, so report the first item's text
+	if offset, ok := nodeOffset(list); ok && !list.IsTight {
+		w := warningAt(offset, source, lineOffsets, "Loose lists may reduce readability")
+		w.Fix = tightListFix(list, source, lineOffsets)
+		return []LintWarning{w}
 	}
-	return warnings
+	return nil
 }
 
-func textRules(n ast.Node, source []byte, lineOffsets []int) []LintWarning {
-	var warnings []LintWarning
-	textNode := n.(*ast.Text)
-	txt := string(textNode.Segment.Value(source))
-	line := getLine(textNode.Segment.Start, lineOffsets)
+// textRule builds a rule that flags text nodes where find reports a
+// match, at the byte index it returns (0 for the start of the text).
+func textRule(find func(txt string) (int, bool), message string) func(ast.Node, []byte, []int) []LintWarning {
+	return fixableTextRule(find, message, nil)
+}
+
+// fixableTextRule is textRule with a fix for each finding, where fix can
+// build one.
+func fixableTextRule(find func(txt string) (int, bool), message string,
+	fix func(t *ast.Text, source []byte, lineOffsets []int) *LintFix) func(ast.Node, []byte, []int) []LintWarning {
+	return func(n ast.Node, source []byte, lineOffsets []int) []LintWarning {
+		textNode := n.(*ast.Text)
+		at, ok := find(string(textNode.Segment.Value(source)))
+		if !ok {
+			return nil
+		}
+		w := warningAt(textNode.Segment.Start+at, source, lineOffsets, message)
+		if fix != nil {
+			w.Fix = fix(textNode, source, lineOffsets)
+		}
+		return []LintWarning{w}
+	}
+}
 
-	if strings.Contains(txt, "TODO") {
-		warnings = append(warnings, LintWarning{Line: line, Message: "Contains unfinished content (TODO)"})
+// multipleSpacesRule looks at the source rather than the text, as inline
+// parsers may split a run of spaces between two text nodes.
+func multipleSpacesRule(n ast.Node, source []byte, lineOffsets []int) []LintWarning {
+	textNode := n.(*ast.Text)
+	runs := spaceRuns(textNode, source)
+	if len(runs) == 0 {
+		return nil
 	}
-	if strings.Contains(txt, "  ") {
-		warnings = append(warnings, LintWarning{Line: line, Message: "Avoid multiple consecutive spaces"})
+	w := warningAt(runs[0][0], source, lineOffsets, "Avoid multiple consecutive spaces")
+	w.Fix = multipleSpacesFix(textNode, source, lineOffsets)
+	return []LintWarning{w}
+}
+
+// trailingHashRule looks at the rest of the paragraph's first line rather
+// than the text, as linkify splits "#Heading ##" before the closing hashes.
+func trailingHashRule(n ast.Node, source []byte, lineOffsets []int) []LintWarning {
+	textNode := n.(*ast.Text)
+	if !startsParagraph(textNode) {
+		return nil
 	}
-	if strings.Count(txt, "*")%2 != 0 || unclosedAsteriskRe.MatchString(txt) {
-		warnings = append(warnings, LintWarning{Line: line, Message: "Unclosed italic/bold formatting"})
+	start, stop := textNode.Segment.Start, textNode.Segment.Start
+	for stop < len(source) && source[stop] != '\n' && source[stop] != '\r' {
+		stop++
 	}
-	if headingNoSpaceRe.MatchString(txt) {
-		warnings = append(warnings, LintWarning{Line: line, Message: "Missing space after hash in heading"})
+	if !trailingHashInHeadingRe.Match(source[start:stop]) {
+		return nil
 	}
-	if trailingHashInHeadingRe.MatchString(txt) {
-		warnings = append(warnings, LintWarning{Line: line, Message: "Avoid trailing '#' in heading"})
+	w := warningAt(start, source, lineOffsets, "Avoid trailing '#' in heading")
+	w.Fix = trailingHashFix(start, stop, source, lineOffsets)
+	return []LintWarning{w}
+}
+
+func containing(substr string) func(string) (int, bool) {
+	return func(txt string) (int, bool) {
+		i := strings.Index(txt, substr)
+		return i, i >= 0
 	}
-	if unclosedParenLinkRe.MatchString(txt) {
-		warnings = append(warnings, LintWarning{Line: line, Message: "Malformed link (missing closing parenthesis)"})
+}
+
+func matching(match func(string) bool) func(string) (int, bool) {
+	return func(txt string) (int, bool) {
+		return 0, match(txt)
 	}
+}
 
-	return warnings
+func unclosedEmphasis(txt string) bool {
+	return strings.Count(txt, "*")%2 != 0 || unclosedAsteriskRe.MatchString(txt)
 }
 
-func linkRules(n ast.Node, _ []byte, lineOffsets []int) []LintWarning {
-	var warnings []LintWarning
+func linkSchemeRule(n ast.Node, source []byte, lineOffsets []int) []LintWarning {
 	link := n.(*ast.Link)
-	if !strings.HasPrefix(string(link.Destination), "http") && link.Lines().Len() > 0 {
-		line := getLine(link.Lines().At(0).Start, lineOffsets)
-		warnings = append(warnings, LintWarning{
-			Line:    line,
-			Message: "Link does not have a valid URL scheme",
-		})
+	if offset, ok := nodeOffset(link); ok && !strings.HasPrefix(string(link.Destination), "http") {
+		return []LintWarning{warningAt(offset, source, lineOffsets, "Link does not have a valid URL scheme")}
+	}
+	return nil
+}
+
+func unclosedMathBlockRule(n ast.Node, source []byte, lineOffsets []int) []LintWarning {
+	math := n.(*MathBlock)
+	if !math.Closed {
+		return []LintWarning{warningAt(math.start, source, lineOffsets,
+			"Unclosed display math block (missing closing $$)")}
 	}
-	return warnings
+	return nil
+}
+
+// mathRule builds a rule that flags math expressions for which bad holds.
+// Unclosed display blocks are left to math-unclosed-block.
+func mathRule(bad func(expr string) bool, message string) func(ast.Node, []byte, []int) []LintWarning {
+	return func(n ast.Node, source []byte, lineOffsets []int) []LintWarning {
+		var expr string
+		var start int
+		switch math := n.(type) {
+		case *MathBlock:
+			if !math.Closed {
+				return nil
+			}
+			expr, start = math.Expression(source), math.start
+		case *InlineMath:
+			expr, start = math.Expression(source), math.Segment.Start
+		}
+		if !bad(expr) {
+			return nil
+		}
+		return []LintWarning{warningAt(start, source, lineOffsets, message)}
+	}
+}
+
+func emptyMath(expr string) bool {
+	return expr == ""
+}
+
+func unbalancedMathBraces(expr string) bool {
+	return expr != "" && !balancedBraces(expr)
+}
+
+func mismatchedMathEnvironments(expr string) bool {
+	return len(mathBeginRe.FindAllString(expr, -1)) != len(mathEndRe.FindAllString(expr, -1))
+}
+
+// balancedBraces ignores escaped braces (\{ and \}) which are literal in TeX.
+func balancedBraces(expr string) bool {
+	depth := 0
+	for i := 0; i < len(expr); i++ {
+		switch expr[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth < 0 {
+				return false
+			}
+		}
+	}
+	return depth == 0
 }
 
 // ----- Utility -----
@@ -212,6 +374,61 @@ func buildLineOffsets(source []byte) []int {
 	return offsets
 }
 
+// NodeLine returns the 1-based source line where n starts. Inline nodes
+// without text of their own fall back to their closest ancestor.
+func NodeLine(n ast.Node, source []byte) int {
+	line, _ := NodePosition(n, source)
+	return line
+}
+
+// NodePosition is NodeLine with the 1-based column as well.
+func NodePosition(n ast.Node, source []byte) (int, int) {
+	offset, ok := nodeOffset(n)
+	if !ok {
+		return 0, 0
+	}
+	lineOffsets := buildLineOffsets(source)
+	line := getLine(offset, lineOffsets)
+	return line, getColumn(offset, line, source, lineOffsets)
+}
+
+func nodeOffset(n ast.Node) (int, bool) {
+	for cur := n; cur != nil; cur = cur.Parent() {
+		if offset, ok := firstOffset(cur); ok {
+			return offset, true
+		}
+	}
+	return 0, false
+}
+
+func firstOffset(n ast.Node) (int, bool) {
+	switch v := n.(type) {
+	case *ast.Text:
+		return v.Segment.Start, true
+	case *ast.RawHTML:
+		if v.Segments.Len() > 0 {
+			return v.Segments.At(0).Start, true
+		}
+	case *ast.FencedCodeBlock:
+		if v.Info != nil {
+			return v.Info.Segment.Start, true
+		}
+	case *WikiLink:
+		return v.Segment.Start, true
+	case *Mention:
+		return v.Segment.Start, true
+	}
+	if n.Type() == ast.TypeBlock && n.Lines().Len() > 0 {
+		return n.Lines().At(0).Start, true
+	}
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if offset, ok := firstOffset(c); ok {
+			return offset, true
+		}
+	}
+	return 0, false
+}
+
 func getLine(offset int, lineOffsets []int) int {
 	for i := len(lineOffsets) - 1; i >= 0; i-- {
 		if offset >= lineOffsets[i] {
@@ -220,3 +437,18 @@ func getLine(offset int, lineOffsets []int) int {
 	}
 	return 1
 }
+
+// getColumn counts characters, not bytes, so columns match what editors show.
+func getColumn(offset, line int, source []byte, lineOffsets []int) int {
+	offset = min(offset, len(source))
+	start := lineOffsets[line-1]
+	if offset < start {
+		return 1
+	}
+	return utf8.RuneCount(source[start:offset]) + 1
+}
+
+func warningAt(offset int, source []byte, lineOffsets []int, message string) LintWarning {
+	line := getLine(offset, lineOffsets)
+	return LintWarning{Line: line, Column: getColumn(offset, line, source, lineOffsets), Message: message}
+}
@@ -0,0 +1,206 @@
+package utils
+
+import (
+	"bytes"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// ----- Math AST Nodes -----
+
+var (
+	KindMathBlock  = ast.NewNodeKind("MathBlock")
+	KindInlineMath = ast.NewNodeKind("InlineMath")
+)
+
+// MathBlock is a display math block fenced by `$$` lines (or a single
+// `$$ … $$` line). Closed is false when the document ended before the
+// closing `$$`.
+type MathBlock struct {
+	ast.BaseBlock
+	Closed bool
+	start  int // byte offset of the opening `$$`
+}
+
+func (n *MathBlock) Kind() ast.NodeKind {
+	return KindMathBlock
+}
+
+func (n *MathBlock) IsRaw() bool {
+	return true
+}
+
+func (n *MathBlock) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// Expression returns the TeX source of the block.
+func (n *MathBlock) Expression(source []byte) string {
+	var buf bytes.Buffer
+	for i := 0; i < n.Lines().Len(); i++ {
+		line := n.Lines().At(i)
+		buf.Write(line.Value(source))
+	}
+	return string(bytes.TrimSpace(buf.Bytes()))
+}
+
+// InlineMath is an inline `$…$` (or `$$…$$`) TeX expression.
+type InlineMath struct {
+	ast.BaseInline
+	Segment text.Segment
+}
+
+func (n *InlineMath) Kind() ast.NodeKind {
+	return KindInlineMath
+}
+
+func (n *InlineMath) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{
+		"Expression": n.Expression(source),
+	}, nil)
+}
+
+// Expression returns the TeX source between the delimiters.
+func (n *InlineMath) Expression(source []byte) string {
+	return string(bytes.TrimSpace(n.Segment.Value(source)))
+}
+
+// ----- Math Parsers -----
+
+type mathBlockParser struct{}
+
+var mathFence = []byte("$$")
+
+func (b *mathBlockParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+func (b *mathBlockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 || !bytes.HasPrefix(line[pos:], mathFence) {
+		return nil, parser.NoChildren
+	}
+
+	node := &MathBlock{start: segment.Start + pos}
+	contentStart := pos + len(mathFence)
+	rest := line[contentStart:]
+
+	// Single-line form: $$ x^2 $$
+	if end := bytes.Index(rest, mathFence); end >= 0 {
+		if !util.IsBlank(rest[end+len(mathFence):]) {
+			return nil, parser.NoChildren
+		}
+		node.Lines().Append(text.NewSegment(segment.Start+contentStart, segment.Start+contentStart+end))
+		node.Closed = true
+	} else if !util.IsBlank(rest) {
+		node.Lines().Append(text.NewSegment(segment.Start+contentStart, segment.Stop))
+	}
+
+	reader.Advance(segment.Len() - 1)
+	return node, parser.NoChildren
+}
+
+func (b *mathBlockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	math := node.(*MathBlock)
+	if math.Closed {
+		return parser.Close
+	}
+
+	line, segment := reader.PeekLine()
+	if line == nil {
+		return parser.Close
+	}
+	trimmed := util.TrimRightSpace(line)
+	if bytes.HasSuffix(trimmed, mathFence) {
+		// Content may share the closing line: x^2 $$
+		content := trimmed[:len(trimmed)-len(mathFence)]
+		if !util.IsBlank(content) {
+			math.Lines().Append(text.NewSegment(segment.Start, segment.Start+len(content)))
+		}
+		math.Closed = true
+		reader.Advance(segment.Len() - 1)
+		return parser.Close
+	}
+
+	seg := segment
+	seg.ForceNewline = true
+	math.Lines().Append(seg)
+	reader.Advance(segment.Len() - 1)
+	return parser.Continue | parser.NoChildren
+}
+
+func (b *mathBlockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (b *mathBlockParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (b *mathBlockParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+type inlineMathParser struct{}
+
+func (p *inlineMathParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+// Parse follows the usual TeX-in-Markdown conventions so prices like
+// "$5 and $10" stay text: the opening `$` must not be followed by a space,
+// and the closing `$` must not follow a space or precede a digit.
+func (p *inlineMathParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, segment := block.PeekLine()
+	opener := 0
+	for opener < len(line) && line[opener] == '$' {
+		opener++
+	}
+	if opener > 2 || opener >= len(line) || (opener == 1 && util.IsSpace(line[opener])) {
+		return nil
+	}
+
+	for i := opener; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++ // skip escaped characters such as \$
+		case '$':
+			closer := i
+			for closer < len(line) && line[closer] == '$' {
+				closer++
+			}
+			if closer-i != opener {
+				i = closer - 1
+				continue
+			}
+			if opener == 1 && (util.IsSpace(line[i-1]) || (closer < len(line) && util.IsNumeric(line[closer]))) {
+				i = closer - 1
+				continue
+			}
+			if i == opener {
+				return nil
+			}
+			node := &InlineMath{Segment: text.NewSegment(segment.Start+opener, segment.Start+i)}
+			block.Advance(closer)
+			return node
+		}
+	}
+	return nil
+}
+
+// ----- Math Extension -----
+
+type mathExtension struct{}
+
+// Math is a goldmark extension that parses `$$…$$` display math into
+// MathBlock nodes and `$…$` inline math into InlineMath nodes.
+var Math goldmark.Extender = &mathExtension{}
+
+func (e *mathExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithBlockParsers(util.Prioritized(&mathBlockParser{}, 750)),
+		parser.WithInlineParsers(util.Prioritized(&inlineMathParser{}, 450)),
+	)
+}
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yuin/goldmark/ast"
+	"testing"
+)
+
+func TestParseMath(t *testing.T) {
+	markdown := "Energy $E = mc^2$ costs $5 and $10.\n\n$$\n\\int_0^1 x\\,dx\n$$\n\n$$ a^2 + b^2 $$\n"
+	doc, source, err := NewDefaultMarkdownParser().Parse(markdown)
+	require.NoError(t, err)
+
+	var inline []string
+	var blocks []string
+	err = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch v := n.(type) {
+		case *InlineMath:
+			inline = append(inline, v.Expression(source))
+		case *MathBlock:
+			assert.True(t, v.Closed)
+			blocks = append(blocks, v.Expression(source))
+		}
+		return ast.WalkContinue, nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"E = mc^2"}, inline)
+	assert.Equal(t, []string{"\\int_0^1 x\\,dx", "a^2 + b^2"}, blocks)
+}
+
+func TestLintMath(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		expected []LintWarning
+	}{
+		{
+			name:     "well formed math",
+			markdown: "Inline $\\frac{a}{b}$ math.\n",
+			expected: nil,
+		},
+		{
+			name:     "unbalanced braces",
+			markdown: "Inline $\\frac{a}{b$ math.\n",
+			expected: []LintWarning{{Rule: "math-unbalanced-braces", Severity: SeverityWarning, Line: 1, Column: 9, Message: "Unbalanced braces in math expression"}},
+		},
+		{
+			name:     "unclosed display block",
+			markdown: "Intro\n\n$$\nx^2\n",
+			expected: []LintWarning{{Rule: "math-unclosed-block", Severity: SeverityError, Line: 3, Column: 1, Message: "Unclosed display math block (missing closing $$)"}},
+		},
+		{
+			name:     "mismatched environments",
+			markdown: "$$\n\\begin{aligned} x &= 1\n$$\n",
+			expected: []LintWarning{{Rule: "math-mismatched-environment", Severity: SeverityWarning, Line: 1, Column: 1, Message: "Mismatched \\begin/\\end environments in math expression"}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			doc, source, err := NewDefaultMarkdownParser().Parse(tc.markdown)
+			require.NoError(t, err)
+
+			warnings, err := NewPetrelMarkdownLinter().Lint(doc, source, LintOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, warnings)
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package utils
+
+import (
+	"fmt"
+	"github.com/yuin/goldmark/ast"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ----- Draft Metrics -----
+
+// wordsPerMinute is the adult silent reading speed reading time assumes.
+const wordsPerMinute = 238
+
+// DraftMetrics describes the length and readability of a draft's prose.
+// Words count every heading, paragraph, list item and table cell; sentence
+// figures and scores come from paragraphs and list items only. Code, math
+// and HTML are left out.
+type DraftMetrics struct {
+	Words             int                 `json:"words"`
+	Sentences         int                 `json:"sentences"`
+	ReadingMinutes    int                 `json:"reading_minutes"`
+	SentenceLengths   SentenceLengthStats `json:"sentence_lengths"`
+	FleschReadingEase float64             `json:"flesch_reading_ease"` // higher is easier, 60-70 is plain English
+	GradeLevel        float64             `json:"grade_level"`         // Flesch-Kincaid US school grade
+}
+
+// SentenceLengthStats is the distribution of sentence lengths in words.
+type SentenceLengthStats struct {
+	Min       int               `json:"min"`
+	Median    int               `json:"median"`
+	Mean      float64           `json:"mean"`
+	Max       int               `json:"max"`
+	Histogram []SentenceLengths `json:"histogram"`
+}
+
+// SentenceLengths counts the sentences of Min to Max words. The last
+// bucket has no Max.
+type SentenceLengths struct {
+	Min   int `json:"min"`
+	Max   int `json:"max,omitempty"`
+	Count int `json:"count"`
+}
+
+// sentenceBuckets are the lower bounds of the histogram buckets.
+var sentenceBuckets = []int{1, 11, 21, 31, 41}
+
+// ComputeDraftMetrics measures the prose in doc.
+func ComputeDraftMetrics(doc ast.Node, source []byte) DraftMetrics {
+	var m DraftMetrics
+	var lengths []int
+	syllables := 0
+
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n.(type) {
+		case *ast.Heading, *extast.TableCell:
+			m.Words += len(proseWords(proseText(n, source)))
+			return ast.WalkSkipChildren, nil
+		case *ast.Paragraph, *ast.TextBlock:
+			for _, sentence := range splitSentences(proseText(n, source)) {
+				m.Words += len(sentence)
+				lengths = append(lengths, len(sentence))
+				for _, word := range sentence {
+					syllables += countSyllables(word)
+				}
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+
+	if m.Words > 0 {
+		m.ReadingMinutes = int(math.Ceil(float64(m.Words) / wordsPerMinute))
+	}
+	m.Sentences = len(lengths)
+	if m.Sentences == 0 {
+		return m
+	}
+
+	m.SentenceLengths = sentenceLengthStats(lengths)
+	words := 0
+	for _, l := range lengths {
+		words += l
+	}
+	wordsPerSentence := float64(words) / float64(m.Sentences)
+	syllablesPerWord := float64(syllables) / float64(words)
+	m.FleschReadingEase = round1(206.835 - 1.015*wordsPerSentence - 84.6*syllablesPerWord)
+	m.GradeLevel = round1(max(0, 0.39*wordsPerSentence+11.8*syllablesPerWord-15.59))
+	return m
+}
+
+func sentenceLengthStats(lengths []int) SentenceLengthStats {
+	sorted := append([]int(nil), lengths...)
+	sort.Ints(sorted)
+	total := 0
+	for _, l := range sorted {
+		total += l
+	}
+
+	stats := SentenceLengthStats{
+		Min:    sorted[0],
+		Median: sorted[len(sorted)/2],
+		Mean:   round1(float64(total) / float64(len(sorted))),
+		Max:    sorted[len(sorted)-1],
+	}
+	for i, lower := range sentenceBuckets {
+		bucket := SentenceLengths{Min: lower}
+		if i+1 < len(sentenceBuckets) {
+			bucket.Max = sentenceBuckets[i+1] - 1
+		}
+		for _, l := range sorted {
+			if l >= lower && (bucket.Max == 0 || l <= bucket.Max) {
+				bucket.Count++
+			}
+		}
+		stats.Histogram = append(stats.Histogram, bucket)
+	}
+	return stats
+}
+
+// proseText is plainText without code spans, math, raw HTML and bare URLs.
+func proseText(n ast.Node, source []byte) string {
+	var sb strings.Builder
+	_ = ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch t := c.(type) {
+		case *ast.CodeSpan, *InlineMath, *ast.RawHTML, *ast.AutoLink:
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			sb.Write(t.Segment.Value(source))
+			if t.SoftLineBreak() || t.HardLineBreak() {
+				sb.WriteByte(' ')
+			}
+		case *ast.String:
+			sb.Write(t.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return sb.String()
+}
+
+// proseWords returns the tokens of text with a letter or digit in them.
+func proseWords(text string) []string {
+	var words []string
+	for _, field := range strings.Fields(text) {
+		if strings.IndexFunc(field, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+			words = append(words, field)
+		}
+	}
+	return words
+}
+
+// sentenceAbbreviations end in a period without ending the sentence.
+var sentenceAbbreviations = map[string]bool{
+	"e.g.": true, "i.e.": true, "vs.": true, "mr.": true, "mrs.": true, "ms.": true, "dr.": true, "no.": true,
+}
+
+// splitSentences splits a block's text into sentences of words. The end
+// of the block ends a sentence too.
+func splitSentences(text string) [][]string {
+	var sentences [][]string
+	var current []string
+	for _, word := range proseWords(text) {
+		current = append(current, word)
+		end := strings.TrimRight(word, `"')]’”`)
+		if strings.ContainsAny(end[max(0, len(end)-1):], ".!?") && !sentenceAbbreviations[strings.ToLower(end)] {
+			sentences = append(sentences, current)
+			current = nil
+		}
+	}
+	if len(current) > 0 {
+		sentences = append(sentences, current)
+	}
+	return sentences
+}
+
+// countSyllables estimates the syllables in an English word from its
+// vowel groups, less a silent final e. Every word has at least one.
+func countSyllables(word string) int {
+	word = strings.ToLower(strings.TrimFunc(word, func(r rune) bool { return !unicode.IsLetter(r) }))
+	count := 0
+	inVowels := false
+	for _, r := range word {
+		vowel := strings.ContainsRune("aeiouy", r)
+		if vowel && !inVowels {
+			count++
+		}
+		inVowels = vowel
+	}
+	if strings.HasSuffix(word, "e") && !strings.HasSuffix(word, "le") && count > 1 {
+		count--
+	}
+	return max(1, count)
+}
+
+func round1(f float64) float64 {
+	return math.Round(f*10) / 10
+}
+
+// ----- Threshold Rules -----
+
+// ReadabilityLimits are the most a team accepts in one draft. Zero means
+// no limit.
+type ReadabilityLimits struct {
+	MaxGrade float64
+	MaxWords int
+}
+
+// readabilityRule binds a document rule that reports the metric over the
+// team's limit, if any.
+func readabilityRule(over func(m DraftMetrics, limits ReadabilityLimits) string) func(LintOptions) LintCheck {
+	return func(opts LintOptions) LintCheck {
+		return func(n ast.Node, source []byte, lineOffsets []int) []LintWarning {
+			if opts.Readability == (ReadabilityLimits{}) {
+				return nil
+			}
+			message := over(ComputeDraftMetrics(n, source), opts.Readability)
+			offset, ok := firstOffset(n)
+			if message == "" || !ok {
+				return nil
+			}
+			return []LintWarning{warningAt(offset, source, lineOffsets, message)}
+		}
+	}
+}
+
+func gradeOverLimit(m DraftMetrics, limits ReadabilityLimits) string {
+	if limits.MaxGrade <= 0 || m.GradeLevel <= limits.MaxGrade {
+		return ""
+	}
+	return fmt.Sprintf("Reading grade level %.1f is above the limit of %g", m.GradeLevel, limits.MaxGrade)
+}
+
+func lengthOverLimit(m DraftMetrics, limits ReadabilityLimits) string {
+	if limits.MaxWords <= 0 || m.Words <= limits.MaxWords {
+		return ""
+	}
+	return fmt.Sprintf("Draft has %d words, over the limit of %d", m.Words, limits.MaxWords)
+}
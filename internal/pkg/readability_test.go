@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"github.com/obi2na/petrel/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func draftMetrics(t *testing.T, markdown string) DraftMetrics {
+	doc, source, err := NewDefaultMarkdownParser().Parse(markdown)
+	require.NoError(t, err)
+	return ComputeDraftMetrics(doc, source)
+}
+
+func TestComputeDraftMetrics(t *testing.T) {
+	m := draftMetrics(t, "# Launch plan\n\n"+
+		"The cat sat on the mat. It was happy!\n\n"+
+		"- Ship the `release-notes` build on Friday.\n"+
+		"- Tell support, e.g. via chat\n\n"+
+		"```go\nfunc main() {}\n```\n\n"+
+		"| Owner | Task |\n| --- | --- |\n| Ann | Review docs |\n")
+
+	assert.Equal(t, 26, m.Words) // 2 heading, 9 paragraph, 10 list, 5 table; no code
+	assert.Equal(t, 4, m.Sentences)
+	assert.Equal(t, 1, m.ReadingMinutes)
+	assert.Equal(t, SentenceLengthStats{
+		Min: 3, Median: 5, Mean: 4.8, Max: 6,
+		Histogram: []SentenceLengths{
+			{Min: 1, Max: 10, Count: 4},
+			{Min: 11, Max: 20},
+			{Min: 21, Max: 30},
+			{Min: 31, Max: 40},
+			{Min: 41},
+		},
+	}, m.SentenceLengths)
+	assert.Greater(t, m.FleschReadingEase, 80.0)
+	assert.Less(t, m.GradeLevel, 4.0)
+}
+
+func TestComputeDraftMetrics_Scores(t *testing.T) {
+	plain := draftMetrics(t, "We ship on Monday. The team is ready. Tests pass.\n")
+	dense := draftMetrics(t, "Comprehensive organizational transformation necessitates "+
+		"considerable interdepartmental collaboration, institutional accountability and "+
+		"continuous evaluation of operational effectiveness across multiple international subsidiaries.\n")
+
+	assert.Greater(t, plain.FleschReadingEase, dense.FleschReadingEase)
+	assert.Less(t, plain.GradeLevel, dense.GradeLevel)
+	assert.Greater(t, dense.GradeLevel, 16.0)
+}
+
+func TestComputeDraftMetrics_ReadingTime(t *testing.T) {
+	m := draftMetrics(t, strings.Repeat("word ", wordsPerMinute+1)+"\n")
+	assert.Equal(t, wordsPerMinute+1, m.Words)
+	assert.Equal(t, 2, m.ReadingMinutes)
+
+	m = draftMetrics(t, "```\ncode only\n```\n")
+	assert.Equal(t, DraftMetrics{}, m)
+}
+
+func TestCountSyllables(t *testing.T) {
+	for word, want := range map[string]int{
+		"cat": 1, "make": 1, "table": 2, "readability": 5, "the": 1, "Petrel's": 2, "42": 1,
+	} {
+		assert.Equal(t, want, countSyllables(word), word)
+	}
+}
+
+func TestLint_ReadabilityLimits(t *testing.T) {
+	markdown := "# Notes\n\nComprehensive organizational transformation necessitates considerable collaboration.\n"
+	doc, source, err := NewDefaultMarkdownParser().Parse(markdown)
+	require.NoError(t, err)
+	linter := NewPetrelMarkdownLinter()
+
+	warnings, err := linter.Lint(doc, source, LintOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	warnings, err = linter.Lint(doc, source, LintOptions{Readability: ReadabilityLimits{MaxGrade: 10, MaxWords: 5}})
+	require.NoError(t, err)
+	require.Len(t, warnings, 2)
+	assert.Equal(t, "readability-grade", warnings[0].Rule)
+	assert.Regexp(t, `^Reading grade level \d+\.\d is above the limit of 10$`, warnings[0].Message)
+	assert.Equal(t, LintWarning{
+		Rule: "draft-length", Severity: SeverityWarning, Line: 1, Column: 3,
+		Message: "Draft has 7 words, over the limit of 5",
+	}, warnings[1])
+}
+
+func TestLintPolicy_Readability(t *testing.T) {
+	policy, err := NewLintPolicy(config.LintConfig{
+		Readability: config.ReadabilityConfig{MaxGrade: 12, MaxWords: 2000},
+		Teams: map[string]config.LintRuleConfig{
+			"ws-support": {Readability: config.ReadabilityConfig{MaxGrade: 8}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ReadabilityLimits{MaxGrade: 8, MaxWords: 2000}, policy.ReadabilityFor("ws-support", "user-1"))
+	assert.Equal(t, ReadabilityLimits{MaxGrade: 12, MaxWords: 2000}, policy.ReadabilityFor("ws-other", "user-1"))
+
+	var none *LintPolicy
+	assert.Equal(t, ReadabilityLimits{}, none.ReadabilityFor("ws-support", "user-1"))
+
+	_, err = NewLintPolicy(config.LintConfig{Readability: config.ReadabilityConfig{MaxWords: -1}})
+	assert.ErrorIs(t, err, ErrInvalidLintRules)
+}
@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"bytes"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ----- Reference AST Nodes -----
+
+var (
+	KindWikiLink = ast.NewNodeKind("WikiLink")
+	KindMention  = ast.NewNodeKind("Mention")
+)
+
+// WikiLink is a `[[Page Title]]` reference to another page in the
+// destination workspace.
+type WikiLink struct {
+	ast.BaseInline
+	Segment text.Segment // the title between the brackets
+}
+
+func (n *WikiLink) Kind() ast.NodeKind {
+	return KindWikiLink
+}
+
+func (n *WikiLink) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{
+		"Title": n.Title(source),
+	}, nil)
+}
+
+// Title returns the referenced page title.
+func (n *WikiLink) Title(source []byte) string {
+	return string(bytes.TrimSpace(n.Segment.Value(source)))
+}
+
+// Mention is an `@person` reference to a member of the destination workspace.
+type Mention struct {
+	ast.BaseInline
+	Segment text.Segment // the handle without the leading `@`
+}
+
+func (n *Mention) Kind() ast.NodeKind {
+	return KindMention
+}
+
+func (n *Mention) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{
+		"Handle": n.Handle(source),
+	}, nil)
+}
+
+// Handle returns the mentioned name, e.g. "jane.doe" for `@jane.doe`.
+func (n *Mention) Handle(source []byte) string {
+	return string(n.Segment.Value(source))
+}
+
+// ----- Reference Parsers -----
+
+type wikiLinkParser struct{}
+
+var (
+	wikiLinkOpen  = []byte("[[")
+	wikiLinkClose = []byte("]]")
+)
+
+func (p *wikiLinkParser) Trigger() []byte {
+	return []byte{'['}
+}
+
+func (p *wikiLinkParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, segment := block.PeekLine()
+	if !bytes.HasPrefix(line, wikiLinkOpen) {
+		return nil
+	}
+	end := bytes.Index(line[len(wikiLinkOpen):], wikiLinkClose)
+	if end < 0 {
+		return nil
+	}
+	title := line[len(wikiLinkOpen) : len(wikiLinkOpen)+end]
+	if util.IsBlank(title) || bytes.ContainsAny(title, "[]") {
+		return nil
+	}
+
+	start := segment.Start + len(wikiLinkOpen)
+	node := &WikiLink{Segment: text.NewSegment(start, start+len(title))}
+	block.Advance(len(wikiLinkOpen) + end + len(wikiLinkClose))
+	return node
+}
+
+type mentionParser struct{}
+
+func (p *mentionParser) Trigger() []byte {
+	return []byte{'@'}
+}
+
+// Parse reads `@handle`, where a handle is letters, digits and inner `.`,
+// `_` or `-`. An `@` straight after a word character is part of an email
+// address or similar, not a mention.
+func (p *mentionParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	if prev := block.PrecendingCharacter(); isHandleRune(prev) || prev == '.' {
+		return nil
+	}
+
+	line, segment := block.PeekLine()
+	end := 1
+	for end < len(line) {
+		r, size := utf8.DecodeRune(line[end:])
+		if !isHandleRune(r) && r != '.' && r != '_' && r != '-' {
+			break
+		}
+		end += size
+	}
+	// Trailing punctuation ends the sentence rather than the handle
+	for end > 1 && bytes.ContainsRune([]byte("._-"), rune(line[end-1])) {
+		end--
+	}
+	if end == 1 {
+		return nil
+	}
+	// "@team@example.com" is still an email address
+	if end < len(line) && line[end] == '@' {
+		return nil
+	}
+
+	node := &Mention{Segment: text.NewSegment(segment.Start+1, segment.Start+end)}
+	block.Advance(end)
+	return node
+}
+
+func isHandleRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// ----- Reference Extension -----
+
+type referenceExtension struct{}
+
+// References is a goldmark extension that parses `[[Page Title]]` wiki-links
+// into WikiLink nodes and `@person` mentions into Mention nodes.
+var References goldmark.Extender = &referenceExtension{}
+
+func (e *referenceExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		// Ahead of the link parser, which also triggers on '['
+		parser.WithInlineParsers(
+			util.Prioritized(&wikiLinkParser{}, 150),
+			util.Prioritized(&mentionParser{}, 460),
+		),
+	)
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yuin/goldmark/ast"
+	"testing"
+)
+
+func TestParseReferences(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		links    []string
+		mentions []string
+	}{
+		{
+			name:     "wiki-link and mention",
+			markdown: "See [[Launch Plan]] and ask @jane.doe.\n",
+			links:    []string{"Launch Plan"},
+			mentions: []string{"jane.doe"},
+		},
+		{
+			name:     "regular links are untouched",
+			markdown: "A [link](https://example.com) and [ref].\n\n[ref]: https://example.com\n",
+		},
+		{
+			name:     "empty or nested brackets",
+			markdown: "Nothing in [[ ]] or [[a [b] c]].\n",
+		},
+		{
+			name:     "email addresses are not mentions",
+			markdown: "Mail jane@example.com or @team@example.com.\n",
+		},
+		{
+			name:     "mention inside emphasis",
+			markdown: "Thanks **@sam_lee**!\n",
+			mentions: []string{"sam_lee"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, source, err := NewDefaultMarkdownParser().Parse(tt.markdown)
+			require.NoError(t, err)
+
+			var links, mentions []string
+			err = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+				if !entering {
+					return ast.WalkContinue, nil
+				}
+				switch v := n.(type) {
+				case *WikiLink:
+					links = append(links, v.Title(source))
+				case *Mention:
+					mentions = append(mentions, v.Handle(source))
+				}
+				return ast.WalkContinue, nil
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.links, links)
+			assert.Equal(t, tt.mentions, mentions)
+		})
+	}
+}
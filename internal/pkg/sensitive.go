@@ -0,0 +1,429 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/obi2na/petrel/config"
+	"github.com/yuin/goldmark/ast"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ----- Findings -----
+
+// SensitiveKind is a type of personal data or secret.
+type SensitiveKind string
+
+const (
+	SensitiveEmail      SensitiveKind = "email"
+	SensitivePhone      SensitiveKind = "phone"
+	SensitiveNationalID SensitiveKind = "national_id"
+	SensitiveCreditCard SensitiveKind = "credit_card"
+	SensitiveAPIKey     SensitiveKind = "api_key"
+	SensitivePrivateKey SensitiveKind = "private_key"
+	SensitiveJWT        SensitiveKind = "jwt"
+	SensitiveSecret     SensitiveKind = "secret" // a high-entropy value assigned to a key, token or password
+)
+
+var sensitiveKindNames = map[SensitiveKind]string{
+	SensitiveEmail:      "email address",
+	SensitivePhone:      "phone number",
+	SensitiveNationalID: "national ID number",
+	SensitiveCreditCard: "credit card number",
+	SensitiveAPIKey:     "API key",
+	SensitivePrivateKey: "private key",
+	SensitiveJWT:        "JSON Web Token",
+	SensitiveSecret:     "secret",
+}
+
+// SensitiveFinding is personal data or a secret found in a document.
+// Start and End are byte offsets into the source; Redaction is the text
+// to replace them with.
+type SensitiveFinding struct {
+	Kind      SensitiveKind
+	Line      int
+	Column    int
+	Start     int
+	End       int
+	Redaction string
+}
+
+// LintWarning reports the finding with its redaction as the fix.
+func (f SensitiveFinding) LintWarning(severity Severity, message string, source []byte) LintWarning {
+	lineOffsets := buildLineOffsets(source)
+	return LintWarning{
+		Rule:     "sensitive-" + strings.ReplaceAll(string(f.Kind), "_", "-"),
+		Severity: severity,
+		Line:     f.Line,
+		Column:   f.Column,
+		Message:  message,
+		Fix: newFix("Redact the "+f.Kind.String(),
+			newTextEdit(f.Start, f.End, f.Redaction, source, lineOffsets)),
+	}
+}
+
+func (k SensitiveKind) String() string {
+	if name, ok := sensitiveKindNames[k]; ok {
+		return name
+	}
+	return string(k)
+}
+
+// ----- Detector -----
+
+type SensitiveContentDetector interface {
+	Detect(doc ast.Node, source []byte) []SensitiveFinding
+	// DetectText looks in text that is not part of a document, such as a
+	// draft's title. Offsets are into text.
+	DetectText(text string) []SensitiveFinding
+}
+
+// sensitiveRule finds one kind of sensitive value. validate, when set,
+// rejects pattern matches that fail a checksum or look like noise; group
+// picks the submatch that holds the value.
+type sensitiveRule struct {
+	kind     SensitiveKind
+	pattern  *regexp.Regexp
+	group    int
+	validate func(value string) bool
+}
+
+// PetrelSensitiveDetector looks for personal data and secrets in the
+// front matter and the text of every leaf block, code included. It scans
+// the block's source rather than its inline nodes, which may split a value
+// in two.
+type PetrelSensitiveDetector struct {
+	rules []sensitiveRule
+}
+
+func NewPetrelSensitiveDetector() *PetrelSensitiveDetector {
+	// Most specific first; a later match that overlaps an earlier one is dropped
+	return &PetrelSensitiveDetector{rules: []sensitiveRule{
+		{kind: SensitivePrivateKey, pattern: regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )*PRIVATE KEY-----(?:[\s\S]*?-----END (?:[A-Z]+ )*PRIVATE KEY-----|[\s\S]*)`)},
+		{kind: SensitiveJWT, pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`), validate: validJWT},
+		{kind: SensitiveAPIKey, pattern: regexp.MustCompile(`\b(?:` +
+			`AKIA[0-9A-Z]{16}` + // AWS access key ID
+			`|gh[pousr]_[A-Za-z0-9]{36,}` + // GitHub
+			`|github_pat_[A-Za-z0-9_]{22,}` +
+			`|xox[abprs]-[A-Za-z0-9-]{10,}` + // Slack
+			`|[sr]k_(?:live|test)_[A-Za-z0-9]{16,}` + // Stripe
+			`|AIza[0-9A-Za-z_-]{35}` + // Google
+			`|sk-(?:proj-|ant-)?[A-Za-z0-9_-]{20,}` + // OpenAI, Anthropic
+			`|secret_[A-Za-z0-9]{43}|ntn_[A-Za-z0-9]{40,}` + // Notion
+			`)`)},
+		{kind: SensitiveSecret, group: 1, validate: highEntropy,
+			pattern: regexp.MustCompile(`(?i)\b[\w-]*?(?:api[_-]?key|secret|token|passw(?:or)?d|access[_-]?key|client[_-]?secret|auth)\w*["']?[ \t]*[:=][ \t]*["']?([A-Za-z0-9/+_=.-]{16,})`)},
+		{kind: SensitiveCreditCard, pattern: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`), validate: validCardNumber},
+		{kind: SensitiveNationalID, pattern: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`), validate: validSSN},
+		{kind: SensitiveNationalID, pattern: regexp.MustCompile(`\b[A-CEGHJ-PR-TW-Z][A-CEGHJ-NPR-TW-Z] ?\d{2} ?\d{2} ?\d{2} ?[A-D]\b`), validate: validNINO},
+		{kind: SensitiveEmail, pattern: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}\b`)},
+		{kind: SensitivePhone, pattern: regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{2,4}\)|\b\d{3})[ .-]\d{3}[ .-]\d{4}\b|\+\d{1,3}(?:[ .-]?\d{2,4}){2,5}\b`), validate: validPhone},
+	}}
+}
+
+// Detect returns the findings in document order.
+func (d *PetrelSensitiveDetector) Detect(doc ast.Node, source []byte) []SensitiveFinding {
+	lineOffsets := buildLineOffsets(source)
+	var findings []SensitiveFinding
+
+	// Front matter leaves Petrel too: its title names the page
+	if start, stop, ok := frontMatterRange(doc, source); ok {
+		for _, f := range d.scan(source, start, stop, nil, lineOffsets) {
+			f.Redaction = strings.Trim(f.Redaction, "[]") // a leading [ would start a YAML list
+			findings = append(findings, f)
+		}
+	}
+
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering || n.Type() != ast.TypeBlock || n.HasChildren() && !isTextBlockWithInlines(n) {
+			return ast.WalkContinue, nil
+		}
+		for _, r := range blockRanges(n) {
+			findings = append(findings, d.scan(source, r[0], r[1], findings, lineOffsets)...)
+		}
+		return ast.WalkSkipChildren, nil
+	})
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Start < findings[j].Start
+	})
+	return findings
+}
+
+// DetectText returns the findings in text in order.
+func (d *PetrelSensitiveDetector) DetectText(text string) []SensitiveFinding {
+	source := []byte(text)
+	findings := d.scan(source, 0, len(source), nil, buildLineOffsets(source))
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Start < findings[j].Start
+	})
+	return findings
+}
+
+// frontMatterRange returns where the YAML of doc's front matter sits in source.
+func frontMatterRange(doc ast.Node, source []byte) (int, int, bool) {
+	if _, ok := GetFrontMatter(doc); !ok {
+		return 0, 0, false
+	}
+	raw, _, ok := splitFrontMatter(source)
+	if !ok {
+		return 0, 0, false
+	}
+	start := bytes.IndexByte(source, '\n') + 1
+	return start, start + len(raw), true
+}
+
+// isTextBlockWithInlines reports whether n is a leaf block whose children
+// are its inline content, such as a paragraph or heading.
+func isTextBlockWithInlines(n ast.Node) bool {
+	return n.FirstChild() != nil && n.FirstChild().Type() == ast.TypeInline
+}
+
+// blockRanges returns the source a leaf block covers, from its first line
+// to its last so values may run across lines. Blocks without lines of
+// their own, such as table cells, fall back to their text nodes.
+func blockRanges(n ast.Node) [][2]int {
+	if lines := n.Lines(); lines.Len() > 0 {
+		return [][2]int{{lines.At(0).Start, lines.At(lines.Len() - 1).Stop}}
+	}
+	var ranges [][2]int
+	_ = ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if t, ok := c.(*ast.Text); ok && entering {
+			if last := len(ranges) - 1; last >= 0 && ranges[last][1] == t.Segment.Start {
+				ranges[last][1] = t.Segment.Stop
+			} else {
+				ranges = append(ranges, [2]int{t.Segment.Start, t.Segment.Stop})
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return ranges
+}
+
+func (d *PetrelSensitiveDetector) scan(source []byte, start, stop int, seen []SensitiveFinding, lineOffsets []int) []SensitiveFinding {
+	text := string(source[start:stop])
+	var found []SensitiveFinding
+	for _, rule := range d.rules {
+		for _, match := range rule.pattern.FindAllStringSubmatchIndex(text, -1) {
+			from, to := match[2*rule.group], match[2*rule.group+1]
+			if from < 0 {
+				continue
+			}
+			value := text[from:to]
+			if rule.validate != nil && !rule.validate(value) {
+				continue
+			}
+			finding := SensitiveFinding{Kind: rule.kind, Start: start + from, End: start + to, Redaction: redaction(rule.kind, value)}
+			if overlapsFinding(finding, seen) || overlapsFinding(finding, found) {
+				continue
+			}
+			finding.Line = getLine(finding.Start, lineOffsets)
+			finding.Column = getColumn(finding.Start, finding.Line, source, lineOffsets)
+			found = append(found, finding)
+		}
+	}
+	return found
+}
+
+func overlapsFinding(f SensitiveFinding, others []SensitiveFinding) bool {
+	for _, o := range others {
+		if f.Start < o.End && o.Start < f.End {
+			return true
+		}
+	}
+	return false
+}
+
+// redaction keeps the last four digits of a card number, which is what
+// receipts show, and nothing of anything else.
+func redaction(kind SensitiveKind, value string) string {
+	if kind == SensitiveCreditCard {
+		digits := onlyDigits(value)
+		return "[REDACTED CARD ending " + digits[len(digits)-4:] + "]"
+	}
+	return "[REDACTED " + strings.ToUpper(string(kind)) + "]"
+}
+
+// ----- Validators -----
+
+func onlyDigits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// luhnValid checks the Luhn checksum all card numbers carry.
+func luhnValid(digits string) bool {
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+var cardPrefixRe = regexp.MustCompile(`^(?:4|5[1-5]|2[2-7]|3[47]|6(?:011|5|4[4-9])|35)`)
+
+func validCardNumber(value string) bool {
+	digits := onlyDigits(value)
+	return len(digits) >= 13 && len(digits) <= 19 && cardPrefixRe.MatchString(digits) && luhnValid(digits)
+}
+
+// validSSN rejects numbers the US never issues: area 000, 666 or 9xx,
+// group 00 and serial 0000.
+func validSSN(value string) bool {
+	area, group, serial := value[0:3], value[4:6], value[7:11]
+	return area != "000" && area != "666" && area[0] != '9' && group != "00" && serial != "0000"
+}
+
+// validNINO rejects the UK National Insurance prefixes never issued.
+func validNINO(value string) bool {
+	switch strings.ToUpper(value[:2]) {
+	case "BG", "GB", "KN", "NK", "NT", "TN", "ZZ":
+		return false
+	}
+	return true
+}
+
+func validPhone(value string) bool {
+	digits := len(onlyDigits(value))
+	return digits >= 10 && digits <= 15
+}
+
+// validJWT checks that the header decodes to JSON naming an algorithm.
+func validJWT(value string) bool {
+	header, err := base64.RawURLEncoding.DecodeString(strings.SplitN(value, ".", 2)[0])
+	if err != nil {
+		return false
+	}
+	var fields map[string]any
+	if json.Unmarshal(header, &fields) != nil {
+		return false
+	}
+	_, ok := fields["alg"]
+	return ok
+}
+
+// minSecretEntropy is the Shannon entropy, in bits per character, above
+// which a value assigned to a key-like name reads as a generated secret
+// rather than a word or placeholder.
+const minSecretEntropy = 3.5
+
+func highEntropy(value string) bool {
+	return shannonEntropy(value) >= minSecretEntropy
+}
+
+func shannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+	entropy := 0.0
+	for _, c := range counts {
+		p := float64(c) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// ----- Policy -----
+
+// SensitiveAction is what staging does with a finding.
+type SensitiveAction string
+
+const (
+	SensitiveWarn   SensitiveAction = "warn"   // report it and stage the draft as is
+	SensitiveRedact SensitiveAction = "redact" // replace it with its redaction before staging
+	SensitiveBlock  SensitiveAction = "block"  // stage nothing
+)
+
+var ErrInvalidSensitivePolicy = errors.New("invalid sensitive content policy")
+
+// SensitivePolicy picks the action for each kind of finding.
+type SensitivePolicy struct {
+	defaultAction SensitiveAction
+	kinds         map[SensitiveKind]SensitiveAction
+}
+
+// NewSensitivePolicy builds the policy from config. Kinds without an
+// action of their own use the default, which is to warn.
+func NewSensitivePolicy(cfg config.SensitiveContentConfig) (*SensitivePolicy, error) {
+	policy := &SensitivePolicy{defaultAction: SensitiveWarn, kinds: make(map[SensitiveKind]SensitiveAction)}
+	if cfg.Action != "" {
+		action, err := parseSensitiveAction(cfg.Action)
+		if err != nil {
+			return nil, err
+		}
+		policy.defaultAction = action
+	}
+	for name, value := range cfg.Kinds {
+		kind := SensitiveKind(strings.ToLower(name))
+		if _, ok := sensitiveKindNames[kind]; !ok {
+			return nil, fmt.Errorf("%w: unknown kind %q", ErrInvalidSensitivePolicy, name)
+		}
+		action, err := parseSensitiveAction(value)
+		if err != nil {
+			return nil, err
+		}
+		policy.kinds[kind] = action
+	}
+	return policy, nil
+}
+
+func parseSensitiveAction(s string) (SensitiveAction, error) {
+	switch action := SensitiveAction(strings.ToLower(strings.TrimSpace(s))); action {
+	case SensitiveWarn, SensitiveRedact, SensitiveBlock:
+		return action, nil
+	default:
+		return "", fmt.Errorf("%w: unknown action %q (want warn, redact or block)", ErrInvalidSensitivePolicy, s)
+	}
+}
+
+// ActionFor returns the action for kind.
+func (p *SensitivePolicy) ActionFor(kind SensitiveKind) SensitiveAction {
+	if p == nil {
+		return SensitiveWarn
+	}
+	if action, ok := p.kinds[kind]; ok {
+		return action
+	}
+	return p.defaultAction
+}
+
+// RedactSensitive replaces each finding with its redaction.
+func RedactSensitive(source []byte, findings []SensitiveFinding) []byte {
+	var out strings.Builder
+	pos := 0
+	for _, f := range findings {
+		if f.Start < pos {
+			continue
+		}
+		out.Write(source[pos:f.Start])
+		out.WriteString(f.Redaction)
+		pos = f.End
+	}
+	out.Write(source[pos:])
+	return []byte(out.String())
+}
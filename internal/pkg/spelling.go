@@ -0,0 +1,606 @@
+package utils
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"github.com/yuin/goldmark/ast"
+	"golang.org/x/text/unicode/norm"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ----- Dictionaries -----
+
+//go:embed dictionaries/*.aff dictionaries/*.dic
+var dictionaryFiles embed.FS
+
+// bundledWordLists are the .dic files every dictionary starts from. They
+// all use the flags in en_US.aff.
+var bundledWordLists = []string{"dictionaries/en_US.dic", "dictionaries/tech.dic"}
+
+// Dictionary is a set of correctly spelled words, read from Hunspell-style
+// word lists: words with affix flags, expanded by the rules of an affix
+// file. A lowercase word matches in any case, a capitalized one such as a
+// name matches as written or in all caps, and any other only as written.
+type Dictionary struct {
+	words  map[string]bool
+	others []*Dictionary // added by With, which leaves them unchanged
+
+	indexOnce sync.Once
+	index     map[rune][]string
+}
+
+// affixRule turns a word into another by stripping strip and adding add at
+// the start (prefixes) or end (suffixes), if the word matches condition.
+type affixRule struct {
+	prefix    bool
+	cross     bool // combines with affixes of the other kind
+	strip     string
+	add       string
+	condition *regexp.Regexp
+}
+
+// affixRules are an affix file's rules by flag.
+type affixRules map[rune][]affixRule
+
+// bundledDictionary is the dictionary built from the bundled word lists.
+var bundledDictionary = sync.OnceValues(func() (*Dictionary, error) {
+	affixes, err := readAffixFile("dictionaries/en_US.aff")
+	if err != nil {
+		return nil, err
+	}
+	d := &Dictionary{words: make(map[string]bool)}
+	for _, name := range bundledWordLists {
+		f, err := dictionaryFiles.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		err = d.addWordList(f, affixes)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return d, nil
+})
+
+func readAffixFile(name string) (affixRules, error) {
+	f, err := dictionaryFiles.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	affixes, err := parseAffixes(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return affixes, nil
+}
+
+// NewDictionary builds a dictionary from .dic entries, such as a team's
+// product names and jargon. An entry is a word, optionally followed by "/"
+// and flags from the bundled en_US affix rules, e.g. "Petrel/M" to accept
+// "Petrel's" too.
+func NewDictionary(entries []string) (*Dictionary, error) {
+	affixes, err := readAffixFile("dictionaries/en_US.aff")
+	if err != nil {
+		return nil, err
+	}
+	d := &Dictionary{words: make(map[string]bool)}
+	for _, entry := range entries {
+		if err := d.addEntry(strings.TrimSpace(entry), affixes); err != nil {
+			return nil, fmt.Errorf("%w: dictionary: %v", ErrInvalidLintRules, err)
+		}
+	}
+	return d, nil
+}
+
+// parseAffixes reads the PFX and SFX rules of a Hunspell affix file. Other
+// directives are ignored.
+func parseAffixes(r io.Reader) (affixRules, error) {
+	affixes := make(affixRules)
+	cross := make(map[rune]bool)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || (fields[0] != "PFX" && fields[0] != "SFX") {
+			continue
+		}
+		flag, size := utf8.DecodeRuneInString(fields[1])
+		if size != len(fields[1]) {
+			return nil, fmt.Errorf("line %d: flag %q is not one character", line, fields[1])
+		}
+		switch len(fields) {
+		case 4: // header: PFX flag cross_product count
+			cross[flag] = fields[2] == "Y"
+			affixes[flag] = nil
+		case 5: // rule: PFX flag strip add condition
+			if _, ok := cross[flag]; !ok {
+				return nil, fmt.Errorf("line %d: rule for flag %q before its header", line, flag)
+			}
+			rule, err := newAffixRule(fields[0] == "PFX", fields[2], fields[3], fields[4])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			rule.cross = cross[flag]
+			affixes[flag] = append(affixes[flag], rule)
+		default:
+			return nil, fmt.Errorf("line %d: malformed affix rule", line)
+		}
+	}
+	return affixes, scanner.Err()
+}
+
+func newAffixRule(prefix bool, strip, add, condition string) (affixRule, error) {
+	if strip == "0" {
+		strip = ""
+	}
+	if add == "0" {
+		add = ""
+	}
+	pattern := "(?:" + condition + ")$"
+	if prefix {
+		pattern = "^(?:" + condition + ")"
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return affixRule{}, fmt.Errorf("condition %q: %w", condition, err)
+	}
+	return affixRule{prefix: prefix, strip: strip, add: add, condition: re}, nil
+}
+
+// apply returns word with the affix, or false when the rule does not apply.
+func (r affixRule) apply(word string) (string, bool) {
+	if !r.condition.MatchString(word) {
+		return "", false
+	}
+	if r.prefix {
+		if !strings.HasPrefix(word, r.strip) {
+			return "", false
+		}
+		return r.add + word[len(r.strip):], true
+	}
+	if !strings.HasSuffix(word, r.strip) {
+		return "", false
+	}
+	return word[:len(word)-len(r.strip)] + r.add, true
+}
+
+// addWordList adds the words of a .dic file, whose first line is the count.
+func (d *Dictionary) addWordList(r io.Reader, affixes affixRules) error {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if line == 1 {
+			if _, err := strconv.Atoi(entry); err == nil {
+				continue
+			}
+		}
+		if entry == "" {
+			continue
+		}
+		if err := d.addEntry(entry, affixes); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// addEntry adds a word and every form its flags make of it.
+func (d *Dictionary) addEntry(entry string, affixes affixRules) error {
+	word, flags, _ := strings.Cut(entry, "/")
+	if word == "" {
+		return fmt.Errorf("empty word")
+	}
+	word = norm.NFC.String(word)
+	d.words[word] = true
+
+	var prefixed []string
+	var crossSuffixes []affixRule
+	for _, flag := range flags {
+		rules, ok := affixes[flag]
+		if !ok {
+			return fmt.Errorf("%q: unknown flag %q", word, flag)
+		}
+		for _, rule := range rules {
+			form, ok := rule.apply(word)
+			if !ok {
+				continue
+			}
+			d.words[form] = true
+			if rule.prefix && rule.cross {
+				prefixed = append(prefixed, form)
+			}
+		}
+		for _, rule := range rules {
+			if !rule.prefix && rule.cross {
+				crossSuffixes = append(crossSuffixes, rule)
+			}
+		}
+	}
+	for _, form := range prefixed {
+		for _, rule := range crossSuffixes {
+			if combined, ok := rule.apply(form); ok {
+				d.words[combined] = true
+			}
+		}
+	}
+	return nil
+}
+
+// With returns d with the words of other added. Neither dictionary is
+// modified.
+func (d *Dictionary) With(other *Dictionary) *Dictionary {
+	if other == nil {
+		return d
+	}
+	if d == nil {
+		return other
+	}
+	return &Dictionary{others: []*Dictionary{d, other}}
+}
+
+// Contains reports whether word is spelled correctly.
+func (d *Dictionary) Contains(word string) bool {
+	if d == nil {
+		return false
+	}
+	word = norm.NFC.String(word) // "é" may be one code point or two
+	lower := strings.ToLower(word)
+	switch {
+	case d.has(word):
+		return true
+	case word == lower:
+		return false
+	case word == strings.ToUpper(word):
+		return d.has(lower) || d.has(capitalize(lower))
+	case word == capitalize(lower):
+		return d.has(lower)
+	}
+	return false
+}
+
+func (d *Dictionary) has(word string) bool {
+	if d.words[word] {
+		return true
+	}
+	for _, other := range d.others {
+		if other.has(word) {
+			return true
+		}
+	}
+	return false
+}
+
+// spellingAlphabet is what suggestions insert and replace.
+const spellingAlphabet = "abcdefghijklmnopqrstuvwxyz'"
+
+// maxSuggestions is how many suggestions a misspelling gets at most.
+const maxSuggestions = 5
+
+// Suggest returns known words one edit from word, or two when there are
+// none: a letter deleted, inserted, replaced or swapped with the next.
+// Words two edits away must start with the same letter, which keeps the
+// search to a slice of the dictionary. Words in the same case, then with
+// the same letters, then with the same first letter come first.
+func (d *Dictionary) Suggest(word string) []string {
+	lower := strings.ToLower(word)
+	known := func(candidates map[string]bool) []string {
+		var out []string
+		for c := range candidates {
+			if form := d.knownForm(word, c); form != "" {
+				out = append(out, form)
+			}
+		}
+		return out
+	}
+
+	suggestions := known(spellingEdits(lower))
+	if len(suggestions) == 0 {
+		suggestions = known(d.withinTwoEdits(lower))
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		for _, closer := range []func(word, s string) bool{sameCase, sameLetters, sameStart} {
+			if ca, cb := closer(word, a), closer(word, b); ca != cb {
+				return ca
+			}
+		}
+		if la, lb := lengthDiff(word, a), lengthDiff(word, b); la != lb {
+			return la < lb
+		}
+		return a < b
+	})
+	return dedupe(suggestions, maxSuggestions)
+}
+
+// knownForm returns candidate in the case of word, or capitalized if only
+// that form is known, or "" when the dictionary has neither.
+func (d *Dictionary) knownForm(word, candidate string) string {
+	if candidate == "" {
+		return ""
+	}
+	form := candidate
+	switch lower := strings.ToLower(word); {
+	case word == strings.ToUpper(word) && word != lower:
+		form = strings.ToUpper(candidate)
+	case word != lower:
+		form = capitalize(candidate)
+	}
+	if d.Contains(form) {
+		return form
+	}
+	if d.Contains(capitalize(candidate)) {
+		return capitalize(candidate)
+	}
+	return ""
+}
+
+// withinTwoEdits returns the lowercased words two edits or fewer from
+// word that start with the same letter.
+func (d *Dictionary) withinTwoEdits(word string) map[string]bool {
+	matches := make(map[string]bool)
+	first, _ := utf8.DecodeRuneInString(word)
+	length := utf8.RuneCountInString(word)
+	d.each(func(dict *Dictionary) {
+		for _, candidate := range dict.byFirstLetter()[first] {
+			if n := utf8.RuneCountInString(candidate); n >= length-2 && n <= length+2 && editDistance(word, candidate) <= 2 {
+				matches[candidate] = true
+			}
+		}
+	})
+	return matches
+}
+
+// each calls fn with d and every dictionary added to it.
+func (d *Dictionary) each(fn func(*Dictionary)) {
+	fn(d)
+	for _, other := range d.others {
+		other.each(fn)
+	}
+}
+
+// byFirstLetter indexes the dictionary's words, lowercased, by first
+// letter. It is built on first use.
+func (d *Dictionary) byFirstLetter() map[rune][]string {
+	d.indexOnce.Do(func() {
+		d.index = make(map[rune][]string)
+		for word := range d.words {
+			lower := strings.ToLower(word)
+			r, _ := utf8.DecodeRuneInString(lower)
+			d.index[r] = append(d.index[r], lower)
+		}
+	})
+	return d.index
+}
+
+// editDistance counts the deletions, insertions, replacements and swaps of
+// adjacent letters that turn a into b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	rows := make([][]int, len(ra)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(rb)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			rows[i][j] = min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				rows[i][j] = min(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+	return rows[len(ra)][len(rb)]
+}
+
+func spellingEdits(word string) map[string]bool {
+	runes := []rune(word)
+	edits := make(map[string]bool)
+	for i := 0; i <= len(runes); i++ {
+		head, tail := string(runes[:i]), runes[i:]
+		if len(tail) > 0 {
+			edits[head+string(tail[1:])] = true
+		}
+		if len(tail) > 1 {
+			edits[head+string(tail[1])+string(tail[0])+string(tail[2:])] = true
+		}
+		for _, r := range spellingAlphabet {
+			edits[head+string(r)+string(tail)] = true
+			if len(tail) > 0 && r != tail[0] {
+				edits[head+string(r)+string(tail[1:])] = true
+			}
+		}
+	}
+	delete(edits, word)
+	return edits
+}
+
+// sameCase reports whether s needed no capital to be known, for a
+// lowercase word.
+func sameCase(word, s string) bool {
+	return word != strings.ToLower(word) || s == strings.ToLower(s)
+}
+
+// sameLetters reports whether s is word with letters swapped.
+func sameLetters(word, s string) bool {
+	a, b := []rune(strings.ToLower(word)), []rune(strings.ToLower(s))
+	sort.Slice(a, func(i, j int) bool { return a[i] < a[j] })
+	sort.Slice(b, func(i, j int) bool { return b[i] < b[j] })
+	return string(a) == string(b)
+}
+
+func sameStart(a, b string) bool {
+	ra, _ := utf8.DecodeRuneInString(strings.ToLower(a))
+	rb, _ := utf8.DecodeRuneInString(strings.ToLower(b))
+	return ra == rb
+}
+
+func lengthDiff(a, b string) int {
+	return max(len(a)-len(b), len(b)-len(a))
+}
+
+func dedupe(words []string, limit int) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, w := range words {
+		if !seen[w] && len(out) < limit {
+			seen[w] = true
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+func capitalize(word string) string {
+	r, size := utf8.DecodeRuneInString(word)
+	return string(unicode.ToUpper(r)) + word[size:]
+}
+
+// ----- Spelling Rule -----
+
+// spellingTokenRe finds the whitespace-separated tokens of a text run.
+var spellingTokenRe = regexp.MustCompile(`\S+`)
+
+// spellingWordRe is a word the rule checks: letters, and any accents
+// written as combining marks, with apostrophes inside. Parts of hyphenated
+// words are checked on their own.
+var spellingWordRe = regexp.MustCompile(`\p{L}[\p{L}\p{M}]*(?:['’]\p{L}[\p{L}\p{M}]*)*`)
+
+// spellingRule binds the spelling check to the bundled word lists plus the
+// team's dictionary and glossary product names. Findings carry
+// suggestions, not fixes: the first suggestion is only a guess.
+func spellingRule(opts LintOptions) LintCheck {
+	bundled, err := bundledDictionary()
+	if err != nil {
+		return func(ast.Node, []byte, []int) []LintWarning { return nil }
+	}
+	dictionary := bundled.With(opts.Dictionary).With(productDictionary(opts.Glossary))
+	suggestions := make(map[string][]string) // per run, as misspellings repeat
+
+	return func(n ast.Node, source []byte, lineOffsets []int) []LintWarning {
+		if insideLink(n) {
+			return nil
+		}
+		start, stop, ok := textRun(n.(*ast.Text), source)
+		if !ok {
+			return nil
+		}
+		var warnings []LintWarning
+		for _, loc := range spellingTokenRe.FindAllIndex(source[start:stop], -1) {
+			token := string(source[start+loc[0] : start+loc[1]])
+			if skipSpellingToken(token) {
+				continue
+			}
+			for _, w := range spellingWordRe.FindAllStringIndex(token, -1) {
+				word := token[w[0]:w[1]]
+				if skipSpellingWord(word) || knownWord(dictionary, word) {
+					continue
+				}
+				if _, ok := suggestions[word]; !ok {
+					suggestions[word] = dictionary.Suggest(word)
+				}
+				warning := warningAt(start+loc[0]+w[0], source, lineOffsets, misspellingMessage(word, suggestions[word]))
+				warning.Suggestions = suggestions[word]
+				warnings = append(warnings, warning)
+			}
+		}
+		return warnings
+	}
+}
+
+func misspellingMessage(word string, suggestions []string) string {
+	if len(suggestions) == 0 {
+		return fmt.Sprintf("Possible misspelling %q", word)
+	}
+	return fmt.Sprintf("Possible misspelling %q; did you mean %q?", word, suggestions[0])
+}
+
+// knownWord looks word up with curly apostrophes straightened, and with a
+// possessive 's dropped for names the lists have no possessive for.
+func knownWord(d *Dictionary, word string) bool {
+	word = strings.ReplaceAll(word, "’", "'")
+	if d.Contains(word) {
+		return true
+	}
+	stem, possessive := strings.CutSuffix(word, "'s")
+	if !possessive {
+		stem, possessive = strings.CutSuffix(word, "'S")
+	}
+	if possessive && d.Contains(stem) {
+		return true
+	}
+	// The word lists spell loanwords such as café and naïve without accents
+	if folded := foldAccents(word); folded != word {
+		return knownWord(d, folded)
+	}
+	return false
+}
+
+// foldAccents strips the accents from word, so "résumé" becomes "resume".
+func foldAccents(word string) string {
+	var sb strings.Builder
+	for _, r := range norm.NFD.String(word) {
+		if !unicode.Is(unicode.Mn, r) {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// skipSpellingToken reports whether a token is a URL, address, path, file
+// name or identifier rather than prose.
+func skipSpellingToken(token string) bool {
+	trimmed := strings.Trim(token, `"'“”‘’()[]*,;:!?.…`)
+	return looksLikeURL(trimmed) || strings.ContainsAny(trimmed, `./\@_=<>{}#|$%&+~^`+"`") ||
+		strings.ContainsFunc(trimmed, unicode.IsDigit)
+}
+
+// skipSpellingWord reports whether a word is too short to judge, an
+// acronym or a mixed-case name such as "iPhone".
+func skipSpellingWord(word string) bool {
+	if utf8.RuneCountInString(word) < 2 {
+		return true
+	}
+	rest := word[utf8.RuneLen([]rune(word)[0]):]
+	return strings.ContainsFunc(rest, unicode.IsUpper)
+}
+
+func insideLink(n ast.Node) bool {
+	for cur := n.Parent(); cur != nil; cur = cur.Parent() {
+		switch cur.(type) {
+		case *ast.Link, *ast.AutoLink, *WikiLink:
+			return true
+		}
+	}
+	return false
+}
+
+// productDictionary accepts the words of the glossary's product names.
+func productDictionary(g *Glossary) *Dictionary {
+	if g == nil || len(g.Products) == 0 {
+		return nil
+	}
+	d := &Dictionary{words: make(map[string]bool)}
+	for _, product := range g.Products {
+		for _, word := range spellingWordRe.FindAllString(product.Replacement, -1) {
+			d.words[word] = true
+		}
+	}
+	return d
+}
@@ -0,0 +1,179 @@
+package utils
+
+import (
+	"github.com/obi2na/petrel/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+// lintSpelling lints markdown with only the spelling rule on.
+func lintSpelling(t *testing.T, opts LintOptions, markdown string) []LintWarning {
+	opts.Rules = LintRuleSet{}
+	for _, r := range lintRules {
+		opts.Rules[r.ID] = RuleOff
+	}
+	opts.Rules["spelling"] = RuleOn
+
+	doc, source, err := NewDefaultMarkdownParser().Parse(markdown)
+	require.NoError(t, err)
+	warnings, err := NewPetrelMarkdownLinter().Lint(doc, source, opts)
+	require.NoError(t, err)
+	return warnings
+}
+
+func TestLint_Spelling(t *testing.T) {
+	warnings := lintSpelling(t, LintOptions{}, "# Release notes\n\nWe recieve the *seperate* files.\n\nRecieve them well-knwon.\n")
+	require.Len(t, warnings, 4)
+
+	assert.Equal(t, LintWarning{
+		Rule: "spelling", Severity: SeverityInfo, Line: 3, Column: 4,
+		Message:     `Possible misspelling "recieve"; did you mean "receive"?`,
+		Suggestions: []string{"receive", "relieve"},
+	}, warnings[0])
+	assert.Equal(t, []int{3, 17}, []int{warnings[1].Line, warnings[1].Column})
+	assert.Equal(t, []string{"separate"}, warnings[1].Suggestions)
+	assert.Equal(t, []string{"Receive", "Relieve"}, warnings[2].Suggestions)
+	assert.Equal(t, []int{5, 19}, []int{warnings[3].Line, warnings[3].Column})
+	assert.Equal(t, "known", warnings[3].Suggestions[0])
+	for _, w := range warnings {
+		assert.Nil(t, w.Fix)
+	}
+}
+
+func TestLint_SpellingIgnores(t *testing.T) {
+	for _, markdown := range []string{
+		"Don't worry, it’s the user's choice and the users' too.\n",
+		"Run `recieve --seperate` first.\n",
+		"```\nrecieve seperate\n```\n",
+		"See [the recieve docs](https://example.com/recieve) or <https://example.com/seperate>.\n",
+		"Visit www.exmaple.com or mail hi@exmaple.com.\n",
+		"Edit confg.yaml, then call recieve_all() and ./bin/seprate.\n",
+		"NASA and the IETF use iPhone and JavaScript.\n",
+		"Ship v2beta on 3rd June.\n",
+		"Our backend webhooks sync the repo config to the workspace.\n",
+		"A naïve résumé from the café.\n",
+		"A naive\u0308 re\u0301sume\u0301 from the cafe\u0301.\n", // accents as combining marks
+		"We deploy to Kubernetes with Terraform, nginx and kubectl.\n",
+	} {
+		assert.Empty(t, lintSpelling(t, LintOptions{}, markdown), markdown)
+	}
+}
+
+func TestLint_SpellingOffByDefault(t *testing.T) {
+	doc, source, err := NewDefaultMarkdownParser().Parse("We recieve the files.\n")
+	require.NoError(t, err)
+	warnings, err := NewPetrelMarkdownLinter().Lint(doc, source, LintOptions{})
+	require.NoError(t, err)
+	for _, w := range warnings {
+		assert.NotEqual(t, "spelling", w.Rule)
+	}
+	assert.Len(t, lintSpelling(t, LintOptions{}, "We recieve the files.\n"), 1)
+	assert.Len(t, lintSpelling(t, LintOptions{}, "A cafë.\n"), 0, "accents are folded, not checked")
+}
+
+func TestLint_SpellingCustomDictionary(t *testing.T) {
+	markdown := "Drafts sync from Quillbase. Quillbase's Notionify ships soon.\n"
+	assert.Len(t, lintSpelling(t, LintOptions{}, markdown), 3)
+
+	dictionary, err := NewDictionary([]string{"Quillbase"})
+	require.NoError(t, err)
+	glossary, err := NewGlossary(config.GlossaryConfig{Products: []string{"Notionify"}})
+	require.NoError(t, err)
+	assert.Empty(t, lintSpelling(t, LintOptions{Dictionary: dictionary, Glossary: glossary}, markdown))
+}
+
+func TestDictionary(t *testing.T) {
+	d, err := NewDictionary([]string{"frobnicate/DGS", "Petrel/M", "GitHub", "Zoe\u0308"})
+	require.NoError(t, err)
+
+	for word, want := range map[string]bool{
+		"frobnicate": true, "frobnicated": true, "frobnicating": true, "frobnicates": true,
+		"Frobnicate": true, "FROBNICATE": true, "frobnicater": false,
+		"Petrel": true, "Petrel's": true, "PETREL": true, "petrel": false,
+		"GitHub": true, "GITHUB": false, "Github": false, "github": false,
+		"Zoë": true, "Zoe\u0308": true,
+	} {
+		assert.Equal(t, want, d.Contains(word), word)
+	}
+
+	var none *Dictionary
+	assert.False(t, none.Contains("frobnicate"))
+	assert.True(t, none.With(d).Contains("frobnicate"))
+	assert.Same(t, d, d.With(nil))
+}
+
+func TestDictionary_Suggest(t *testing.T) {
+	bundled, err := bundledDictionary()
+	require.NoError(t, err)
+	for word, want := range map[string]string{
+		"teh": "the", "wrold": "world", "definately": "definitely", "Londn": "London", "RECIEVE": "RECEIVE",
+	} {
+		suggestions := bundled.Suggest(word)
+		require.NotEmpty(t, suggestions, word)
+		assert.Equal(t, want, suggestions[0], word)
+	}
+	assert.LessOrEqual(t, len(bundled.Suggest("ab")), maxSuggestions)
+	assert.Empty(t, bundled.Suggest("qzxwvjkqzxwvjk"))
+}
+
+func TestParseAffixes(t *testing.T) {
+	affixes, err := parseAffixes(strings.NewReader("SET UTF-8\n" +
+		"PFX U Y 1\nPFX U 0 un .\n" +
+		"SFX D Y 2\nSFX D 0 d e\nSFX D 0 ed [^e]\n" +
+		"SFX V N 1\nSFX V e ive e\n"))
+	require.NoError(t, err)
+
+	d := &Dictionary{words: make(map[string]bool)}
+	require.NoError(t, d.addEntry("lock/UD", affixes))
+	require.NoError(t, d.addEntry("abuse/UVD", affixes))
+	for _, word := range []string{"lock", "unlock", "locked", "unlocked", "abuse", "abused", "abusive", "unabused", "unabuse"} {
+		assert.True(t, d.Contains(word), word)
+	}
+	assert.False(t, d.Contains("unabusive"), "V does not combine with prefixes")
+
+	_, err = parseAffixes(strings.NewReader("SFX D 0 ed .\n"))
+	assert.ErrorContains(t, err, "before its header")
+	_, err = parseAffixes(strings.NewReader("SFX D Y 1\nSFX D 0 ed [\n"))
+	assert.ErrorContains(t, err, "line 2")
+}
+
+func TestNewDictionary(t *testing.T) {
+	_, err := NewDictionary([]string{"frobnicate/Q"})
+	assert.ErrorIs(t, err, ErrInvalidLintRules)
+	assert.ErrorContains(t, err, `"frobnicate": unknown flag 'Q'`)
+
+	_, err = NewDictionary([]string{" "})
+	assert.ErrorIs(t, err, ErrInvalidLintRules)
+}
+
+func TestLintPolicy_Dictionary(t *testing.T) {
+	policy, err := NewLintPolicy(config.LintConfig{
+		Dictionary: []string{"Petrel"},
+		Teams: map[string]config.LintRuleConfig{
+			"ws-brand": {Dictionary: []string{"Quillbase"}},
+		},
+		Users: map[string]config.LintRuleConfig{
+			"user-1": {Dictionary: []string{"frobnicate/S"}},
+		},
+	})
+	require.NoError(t, err)
+
+	d := policy.DictionaryFor("WS-BRAND", "user-1")
+	for _, word := range []string{"Petrel", "Quillbase", "frobnicates"} {
+		assert.True(t, d.Contains(word), word)
+	}
+	d = policy.DictionaryFor("ws-other", "user-2")
+	assert.True(t, d.Contains("Petrel"))
+	assert.False(t, d.Contains("Quillbase"))
+
+	var none *LintPolicy
+	assert.Nil(t, none.DictionaryFor("ws-brand", "user-1"))
+
+	_, err = NewLintPolicy(config.LintConfig{Teams: map[string]config.LintRuleConfig{
+		"ws-brand": {Dictionary: []string{"Quillbase/Q"}},
+	}})
+	assert.ErrorIs(t, err, ErrInvalidLintRules)
+	assert.ErrorContains(t, err, "team ws-brand")
+}
@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"fmt"
+	"github.com/yuin/goldmark/ast"
+	"strings"
+)
+
+// ----- Structural Rules -----
+
+// longParagraphWords is the most words a paragraph has before long-paragraph
+// flags it.
+const longParagraphWords = 150
+
+// skippedHeadingLevelRule flags a heading more than one level below the
+// heading before it, as in h1 followed by h3.
+func skippedHeadingLevelRule(n ast.Node, source []byte, lineOffsets []int) []LintWarning {
+	heading := n.(*ast.Heading)
+	offset, ok := headingOffset(heading)
+	prev := previousHeading(heading)
+	if !ok || prev == nil || heading.Level <= prev.Level+1 {
+		return nil
+	}
+	return []LintWarning{warningAt(offset, source, lineOffsets,
+		fmt.Sprintf("Heading level skips from h%d to h%d", prev.Level, heading.Level))}
+}
+
+// duplicateHeadingRule flags a heading with the same text and level as an
+// earlier heading in the same section.
+func duplicateHeadingRule(n ast.Node, source []byte, lineOffsets []int) []LintWarning {
+	heading := n.(*ast.Heading)
+	offset, ok := headingOffset(heading)
+	title := strings.TrimSpace(plainText(heading, source))
+	if !ok || title == "" {
+		return nil
+	}
+	for s := heading.PreviousSibling(); s != nil; s = s.PreviousSibling() {
+		prev, ok := s.(*ast.Heading)
+		if !ok {
+			continue
+		}
+		if prev.Level < heading.Level {
+			break // the parent section starts here
+		}
+		if prev.Level == heading.Level && strings.EqualFold(strings.TrimSpace(plainText(prev, source)), title) {
+			return []LintWarning{warningAt(offset, source, lineOffsets,
+				fmt.Sprintf("Duplicate heading %q in the same section (first on line %d)", title, NodeLine(prev, source)))}
+		}
+	}
+	return nil
+}
+
+// missingTitleRule flags a document whose first heading is not an h1. A
+// title in the front matter counts.
+func missingTitleRule(n ast.Node, source []byte, lineOffsets []int) []LintWarning {
+	if fm, ok := GetFrontMatter(n); ok && fm.Title != "" {
+		return nil
+	}
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		heading, ok := c.(*ast.Heading)
+		if !ok {
+			continue
+		}
+		if heading.Level == 1 {
+			return nil
+		}
+		if offset, ok := headingOffset(heading); ok {
+			return []LintWarning{warningAt(offset, source, lineOffsets,
+				fmt.Sprintf("Document starts with an h%d instead of a title heading (h1)", heading.Level))}
+		}
+		break
+	}
+	if offset, ok := firstOffset(n); ok {
+		return []LintWarning{warningAt(offset, source, lineOffsets, "Document has no title heading (h1)")}
+	}
+	return nil
+}
+
+// emptySectionRule flags a heading followed by a heading of the same level
+// or higher, or by nothing at all.
+func emptySectionRule(n ast.Node, source []byte, lineOffsets []int) []LintWarning {
+	heading := n.(*ast.Heading)
+	offset, ok := headingOffset(heading)
+	if !ok {
+		return nil
+	}
+	next, isHeading := heading.NextSibling().(*ast.Heading)
+	if heading.NextSibling() != nil && (!isHeading || next.Level > heading.Level) {
+		return nil
+	}
+	return []LintWarning{warningAt(offset, source, lineOffsets, "Section has no content")}
+}
+
+func longParagraphRule(n ast.Node, source []byte, lineOffsets []int) []LintWarning {
+	paragraph := n.(*ast.Paragraph)
+	words := len(strings.Fields(plainText(paragraph, source)))
+	if words <= longParagraphWords || paragraph.Lines().Len() == 0 {
+		return nil
+	}
+	return []LintWarning{warningAt(paragraph.Lines().At(0).Start, source, lineOffsets,
+		fmt.Sprintf("Paragraph has %d words; consider splitting paragraphs over %d", words, longParagraphWords))}
+}
+
+func singleItemListRule(n ast.Node, source []byte, lineOffsets []int) []LintWarning {
+	list := n.(*ast.List)
+	if offset, ok := nodeOffset(list); ok && list.ChildCount() == 1 {
+		return []LintWarning{warningAt(offset, source, lineOffsets, "List has a single item")}
+	}
+	return nil
+}
+
+// ----- Helpers -----
+
+// headingOffset is where the heading's text starts. Empty headings have
+// no lines and so no position of their own.
+func headingOffset(heading *ast.Heading) (int, bool) {
+	if heading.Lines().Len() == 0 {
+		return 0, false
+	}
+	return heading.Lines().At(0).Start, true
+}
+
+// previousHeading returns the closest heading before n among its siblings.
+func previousHeading(n ast.Node) *ast.Heading {
+	for s := n.PreviousSibling(); s != nil; s = s.PreviousSibling() {
+		if heading, ok := s.(*ast.Heading); ok {
+			return heading
+		}
+	}
+	return nil
+}
+
+// plainText returns the text of n's inline content, with line breaks as
+// spaces.
+func plainText(n ast.Node, source []byte) string {
+	var sb strings.Builder
+	_ = ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch t := c.(type) {
+		case *ast.Text:
+			sb.Write(t.Segment.Value(source))
+			if t.SoftLineBreak() || t.HardLineBreak() {
+				sb.WriteByte(' ')
+			}
+		case *ast.String:
+			sb.Write(t.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return sb.String()
+}
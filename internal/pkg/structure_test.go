@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+// lintRule lints markdown with every rule off except rule.
+func lintRule(t *testing.T, rule, markdown string) []LintWarning {
+	rules := LintRuleSet{}
+	for _, r := range lintRules {
+		rules[r.ID] = RuleOff
+	}
+	rules[rule] = RuleOn
+
+	doc, source, err := NewDefaultMarkdownParser().Parse(markdown)
+	require.NoError(t, err)
+	warnings, err := NewPetrelMarkdownLinter().Lint(doc, source, LintOptions{Rules: rules})
+	require.NoError(t, err)
+	return warnings
+}
+
+func TestLint_StructuralRules(t *testing.T) {
+	type position struct{ Line, Column int }
+	long := strings.Repeat("word ", longParagraphWords+1)
+
+	tests := []struct {
+		name     string
+		rule     string
+		markdown string
+		expected []position
+	}{
+		{"skipped level", "heading-skipped-level", "# Title\n\n### Details\n\ntext\n", []position{{3, 5}}},
+		{"one level down and back up", "heading-skipped-level", "# Title\n\n## A\n\n### B\n\n## C\n", nil},
+		{"duplicate siblings", "heading-duplicate", "# Title\n\n## Setup\n\nA\n\n## Usage\n\nB\n\n## setup\n\nC\n", []position{{11, 4}}},
+		{"same heading in other sections", "heading-duplicate", "# Title\n\n## Linux\n\n### Setup\n\nA\n\n## Mac\n\n### Setup\n\nB\n", nil},
+		{"no headings", "missing-title", "Just some text.\n", []position{{1, 1}}},
+		{"starts with h2", "missing-title", "Intro.\n\n## Background\n\nText.\n", []position{{3, 4}}},
+		{"title heading", "missing-title", "# Title\n\nText.\n", nil},
+		{"front matter title", "missing-title", "---\ntitle: Report\n---\nText.\n", nil},
+		{"empty sections", "empty-section", "# Title\n\n## Empty\n\n## Full\n\nText.\n\n## Last\n", []position{{3, 4}, {9, 4}}},
+		{"subsection is content", "empty-section", "# Title\n\n## Part\n\n### Detail\n\nText.\n", nil},
+		{"long paragraph", "long-paragraph", "# Title\n\n" + long + "\n", []position{{3, 1}}},
+		{"short paragraph", "long-paragraph", "# Title\n\nA few words.\n", nil},
+		{"single item list", "single-item-list", "# Title\n\n- only\n\n1. one\n2. two\n", []position{{3, 3}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []position
+			for _, w := range lintRule(t, tc.rule, tc.markdown) {
+				assert.Equal(t, tc.rule, w.Rule)
+				got = append(got, position{w.Line, w.Column})
+			}
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestLint_StructuralMessages(t *testing.T) {
+	warnings := lintRule(t, "heading-skipped-level", "# Title\n\n#### Deep\n")
+	require.Len(t, warnings, 1)
+	assert.Equal(t, "Heading level skips from h1 to h4", warnings[0].Message)
+
+	warnings = lintRule(t, "heading-duplicate", "# Title\n\n## *Setup*\n\nA\n\n## Setup\n")
+	require.Len(t, warnings, 1)
+	assert.Equal(t, `Duplicate heading "Setup" in the same section (first on line 3)`, warnings[0].Message)
+}
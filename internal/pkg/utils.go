@@ -303,6 +303,9 @@ type OAuthService[T any] interface {
 
 type NotionApiClient interface {
 	CreatePage(ctx context.Context, token string, req *notionapi.PageCreateRequest) (*notionapi.Page, error)
+	AppendBlockChildren(ctx context.Context, token string, blockID notionapi.BlockID, req *notionapi.AppendBlockChildrenRequest) (*notionapi.AppendBlockChildrenResponse, error)
+	Search(ctx context.Context, token string, req *notionapi.SearchRequest) (*notionapi.SearchResponse, error)
+	ListUsers(ctx context.Context, token string, pagination *notionapi.Pagination) (*notionapi.UsersListResponse, error)
 }
 
 type JomeiClient struct{}
@@ -316,6 +319,21 @@ func (j *JomeiClient) CreatePage(ctx context.Context, token string, req *notiona
 	return client.Page.Create(ctx, req)
 }
 
+func (j *JomeiClient) AppendBlockChildren(ctx context.Context, token string, blockID notionapi.BlockID, req *notionapi.AppendBlockChildrenRequest) (*notionapi.AppendBlockChildrenResponse, error) {
+	client := notionapi.NewClient(notionapi.Token(token))
+	return client.Block.AppendChildren(ctx, blockID, req)
+}
+
+func (j *JomeiClient) Search(ctx context.Context, token string, req *notionapi.SearchRequest) (*notionapi.SearchResponse, error) {
+	client := notionapi.NewClient(notionapi.Token(token))
+	return client.Search.Do(ctx, req)
+}
+
+func (j *JomeiClient) ListUsers(ctx context.Context, token string, pagination *notionapi.Pagination) (*notionapi.UsersListResponse, error) {
+	client := notionapi.NewClient(notionapi.Token(token))
+	return client.User.List(ctx, pagination)
+}
+
 func BuildNotionDraftRepoUrl(pageID string) string {
 	return "https://www.notion.so/" + strings.ReplaceAll(pageID, "-", "")
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/obi2na/petrel/internal/service/manuscript"
 	"github.com/obi2na/petrel/internal/service/notion"
 	"github.com/obi2na/petrel/internal/service/user"
+	"log"
 	"net/http"
 	"time"
 )
@@ -31,14 +32,31 @@ func NewServiceContainer(db *pgxpool.Pool, cache utils.Cache) *ServiceContainer
 	notionApiClient := utils.NewJomeiClient()
 	notionMapper := notion.NewPetrelMarkdownToNotionMapper()
 	notionMapper.RegisterMappers()
+	extensions, err := notion.LoadExtensions(config.C.Markdown.Extensions)
+	if err != nil {
+		log.Fatalf("Invalid markdown extension config: %v", err)
+	}
+	parser := utils.NewDefaultMarkdownParser(notion.ApplyExtensions(notionMapper, extensions)...)
+	linkRules, err := notion.NewLinkRuleSet(config.C.Notion.LinkPreviews)
+	if err != nil {
+		log.Fatalf("Invalid link preview config: %v", err)
+	}
+	lintPolicy, err := utils.NewLintPolicy(config.C.Lint)
+	if err != nil {
+		log.Fatalf("Invalid lint config: %v", err)
+	}
+	sensitivePolicy, err := utils.NewSensitivePolicy(config.C.SensitiveContent)
+	if err != nil {
+		log.Fatalf("Invalid sensitive content config: %v", err)
+	}
 
 	// create service singletons
 	userSvc := userservice.NewUserService(db, cache, utils.NewJWTProvider())
 	authSvc := authService.NewAuthService(config.C.Auth0, httpClient, userSvc)
 	notionOauthSvc := notion.NewNotionOAuthService(httpClient)
 	notionDbSvc := notion.NewNotionDatabaseService(db, httpClient, notionApiClient)
-	notionDraftSvc := notion.NewNotionDraftService(notionApiClient, notionMapper)
-	manuscriptSvc := manuscript.NewManuscriptService(notionDbSvc, notionDraftSvc)
+	notionDraftSvc := notion.NewNotionDraftService(notionApiClient, notionMapper, linkRules, notionDbSvc)
+	manuscriptSvc := manuscript.NewManuscriptService(notionDbSvc, notionDraftSvc, parser, lintPolicy, sensitivePolicy)
 	notionIntegrationService := notion.NewIntegrationService(notionOauthSvc, notionDbSvc, utils.NewJWTProvider())
 
 	return &ServiceContainer{
@@ -2,13 +2,16 @@ package manuscript
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/obi2na/petrel/internal/logger"
 	"github.com/obi2na/petrel/internal/models"
 	utils "github.com/obi2na/petrel/internal/pkg"
 	"github.com/obi2na/petrel/internal/service/notion"
+	"github.com/yuin/goldmark/ast"
 	"go.uber.org/zap"
+	"slices"
 	"strings"
 )
 
@@ -17,8 +20,16 @@ import (
 
 type Service interface {
 	StageDraft(ctx context.Context, userID uuid.UUID, req petrelmodels.CreateDraftRequest) (petrelmodels.CreateDraftResponse, error)
+	Lint(ctx context.Context, userID uuid.UUID, req petrelmodels.LintRequest) (petrelmodels.LintResponse, error)
 }
 
+// ErrInvalidMarkdown is returned when the Markdown or its front matter cannot be parsed.
+var ErrInvalidMarkdown = errors.New("markdown invalid")
+
+// ErrMissingFields is returned when a required field is in neither the
+// request nor the Markdown's front matter.
+var ErrMissingFields = errors.New("draft request incomplete")
+
 type WorkspaceValidator interface {
 	UserHasWorkspace(ctx context.Context, userID uuid.UUID, workspaceID string) (petrelmodels.UserIntegration, bool)
 }
@@ -37,10 +48,17 @@ type ManuscriptService struct {
 	WorkspaceValidatorMap map[string]WorkspaceValidator
 	Parser                utils.Parser
 	Linter                utils.MarkdownLinter
+	LintPolicy            *utils.LintPolicy
+	Detector              utils.SensitiveContentDetector
+	SensitivePolicy       *utils.SensitivePolicy
 	NotionDraftService    notion.DraftService
+	// Platforms are what each platform's mapper carries over, by the
+	// platform's name in destinations.
+	Platforms map[string]utils.PlatformCapabilities
 }
 
-func NewManuscriptService(notionSvc *notion.NotionDatabaseService, notionDraftService *notion.NotionDraftService) *ManuscriptService {
+func NewManuscriptService(notionSvc *notion.NotionDatabaseService, notionDraftService *notion.NotionDraftService, parser utils.Parser,
+	lintPolicy *utils.LintPolicy, sensitivePolicy *utils.SensitivePolicy) *ManuscriptService {
 
 	validatorMap := map[string]WorkspaceValidator{
 		"notion": notionSvc,
@@ -49,13 +67,21 @@ func NewManuscriptService(notionSvc *notion.NotionDatabaseService, notionDraftSe
 	notionMapper := notion.NewPetrelMarkdownToNotionMapper()
 	notionMapper.RegisterMappers()
 
+	platforms := map[string]utils.PlatformCapabilities{
+		"notion": notionDraftService.Capabilities(),
+	}
+
 	return &ManuscriptService{
 		// dependencies injected here
 		NotionDbSvc:           notionSvc,
 		WorkspaceValidatorMap: validatorMap,
-		Parser:                utils.NewDefaultMarkdownParser(),
+		Parser:                parser,
 		Linter:                utils.NewPetrelMarkdownLinter(),
+		LintPolicy:            lintPolicy,
+		Detector:              utils.NewPetrelSensitiveDetector(),
+		SensitivePolicy:       sensitivePolicy,
 		NotionDraftService:    notionDraftService,
+		Platforms:             platforms,
 	}
 }
 
@@ -120,7 +146,30 @@ func (s *ManuscriptService) validateDestinations(ctx context.Context, userID uui
 }
 
 func (s *ManuscriptService) StageDraft(ctx context.Context, userID uuid.UUID, req petrelmodels.CreateDraftRequest) (petrelmodels.CreateDraftResponse, error) {
-	// 1. Validate destinations
+	// 1. Parse markdown into AST; front matter may fill in the rest of the request
+	doc, source, err := s.Parser.Parse(req.Markdown)
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrInvalidMarkdown, err)
+		logger.With(ctx).Error("markdown validation failed", zap.Error(err))
+		return petrelmodels.CreateDraftResponse{
+			Status: "fail",
+			Drafts: []petrelmodels.DraftResultEntry{}, // No drafts created
+		}, err
+	}
+	requestTitle := req.Title // a title from the front matter is screened with the source
+	if frontMatter, ok := utils.GetFrontMatter(doc); ok {
+		applyFrontMatter(&req, frontMatter)
+	}
+	if missing := missingRequestFields(req); len(missing) > 0 {
+		err := fmt.Errorf("%w: %s required in the request or the markdown front matter", ErrMissingFields, strings.Join(missing, " and "))
+		logger.With(ctx).Error("draft request incomplete", zap.Error(err))
+		return petrelmodels.CreateDraftResponse{
+			Status: "fail",
+			Drafts: []petrelmodels.DraftResultEntry{}, // No drafts created
+		}, err
+	}
+
+	// 2. Validate destinations
 	validated, validationErrors := s.validateDestinations(ctx, userID, req.Destinations)
 	if len(validationErrors) > 0 {
 		// Combine all validation messages into one error
@@ -129,26 +178,70 @@ func (s *ManuscriptService) StageDraft(ctx context.Context, userID uuid.UUID, re
 		return petrelmodels.CreateDraftResponse{
 			Status: "fail",
 			Drafts: []petrelmodels.DraftResultEntry{}, // No drafts created
-		}, fmt.Errorf(errMsg)
+		}, errors.New(errMsg)
 	}
 
-	// TODO: 2. Parse markdown into AST
-	doc, source, err := s.Parser.Parse(req.Markdown)
+	// Measure once; every destination gets the same draft
+	metrics := utils.ComputeDraftMetrics(doc, source)
+
+	// Lint under each destination team's rule set before anything reaches a platform
+	notionDestinations := validated["notion"]
+	lintResults, err := s.lintDestinations(userID, notionDestinations, doc, source, req)
 	if err != nil {
-		err = fmt.Errorf("markdown invalid: %w", err)
-		logger.With(ctx).Error("markdown validation failed", zap.Error(err))
+		logger.With(ctx).Error("linting draft failed", zap.Error(err))
 		return petrelmodels.CreateDraftResponse{
 			Status: "fail",
 			Drafts: []petrelmodels.DraftResultEntry{}, // No drafts created
 		}, err
 	}
-
-	// Walk AST and collect warnings
-	_, err = s.Linter.Lint(doc, source)
+	// Look for personal data and secrets before anything leaves Petrel
+	screening := append(s.screenSensitive(doc, source), s.screenTitle(requestTitle)...)
+	for i := range lintResults {
+		lintResults[i].warnings = append(lintResults[i].warnings, screening.warnings()...)
+	}
+	if drafts, err := lintFailures(notionDestinations, lintResults); err != nil {
+		logger.With(ctx).Warn("draft blocked by lint findings", zap.Error(err))
+		return petrelmodels.CreateDraftResponse{
+			Status: "fail",
+			Drafts: withMetrics(drafts, &metrics),
+		}, err
+	}
+	if blocked := screening.count(utils.SensitiveBlock); blocked > 0 {
+		err := fmt.Errorf("%w: %d finding(s)", ErrSensitiveContent, blocked)
+		logger.With(ctx).Warn("draft blocked by sensitive content policy", zap.Error(err))
+		return petrelmodels.CreateDraftResponse{
+			Status: "fail",
+			Drafts: withMetrics(sensitiveFailures(notionDestinations, lintResults, err), &metrics),
+		}, err
+	}
+	if screening.count(utils.SensitiveRedact) > 0 {
+		if findings := screening.findings(utils.SensitiveRedact); len(findings) > 0 {
+			redacted := utils.RedactSensitive(source, findings)
+			if doc, source, err = s.Parser.Parse(string(redacted)); err != nil {
+				err = fmt.Errorf("%w after redaction: %w", ErrInvalidMarkdown, err)
+				logger.With(ctx).Error("redacted markdown invalid", zap.Error(err))
+				return petrelmodels.CreateDraftResponse{
+					Status: "fail",
+					Drafts: []petrelmodels.DraftResultEntry{}, // No drafts created
+				}, err
+			}
+		}
+		req.Title = s.redactTitle(req.Title)
+		logger.With(ctx).Info("Redacted sensitive content", zap.Int("findings", screening.count(utils.SensitiveRedact)))
+	}
 
 	// TODO: 3. Route draft to each platform's DraftService (e.g. NotionDraftService.StageDraft)
-	notionDestinations := validated["notion"]
-	draftResponse, err := s.NotionDraftService.StageDraft(ctx, userID, notionDestinations, doc, source)
+	draftResponse, err := s.NotionDraftService.StageDraft(ctx, userID, notionDestinations, doc, source,
+		petrelmodels.StageOptions{Title: req.Title, Strict: req.Strict, Metrics: &metrics})
+	withLintWarnings(draftResponse, lintResults)
+	withMetrics(draftResponse, &metrics)
+	if err != nil {
+		logger.With(ctx).Error("staging draft failed", zap.Error(err))
+		return petrelmodels.CreateDraftResponse{
+			Status: stagingStatus(draftResponse),
+			Drafts: draftResponse,
+		}, err
+	}
 
 	// TODO: 4. Collect DraftResultEntry per platform
 	// TODO: 5. Return combined CreateDraftResponse
@@ -163,3 +256,324 @@ func (s *ManuscriptService) StageDraft(ctx context.Context, userID uuid.UUID, re
 
 	return response, nil
 }
+
+// ErrLintFailed is returned when lint finds something at or above the
+// fail_on threshold, before any destination is staged.
+var ErrLintFailed = errors.New("draft has lint findings at or above the fail_on threshold")
+
+// destinationLint is the lint outcome for one destination.
+type destinationLint struct {
+	warnings []utils.LintWarning
+	blocking int // findings at or above the fail_on threshold
+}
+
+// lintDestinations lints the draft once per destination, as each team may
+// have its own rule set and threshold.
+func (s *ManuscriptService) lintDestinations(userID uuid.UUID, destinations []petrelmodels.ValidatedDestination,
+	doc ast.Node, source []byte, req petrelmodels.CreateDraftRequest) ([]destinationLint, error) {
+	requested, err := utils.ParseFailOn(req.FailOn)
+	if err != nil {
+		return nil, fmt.Errorf("%w: fail_on: %v", utils.ErrInvalidLintRules, err)
+	}
+
+	results := make([]destinationLint, len(destinations))
+	for i, destination := range destinations {
+		warnings, err := s.Linter.Lint(doc, source, s.lintOptions(userID, destination.Workspace, "notion", req.LintRules))
+		if err != nil {
+			return nil, err
+		}
+		results[i].warnings = warnings
+
+		failOn := utils.StricterFailOn(requested, s.LintPolicy.FailOnFor(destination.Workspace, userID.String()))
+		if failOn == "" {
+			continue
+		}
+		for _, w := range warnings {
+			if w.Severity.AtLeast(failOn) {
+				results[i].blocking++
+			}
+		}
+	}
+	return results, nil
+}
+
+// lintRules layers the rule sets that apply to a draft: the configured
+// defaults, then the destination team's, the author's and the request's.
+func (s *ManuscriptService) lintRules(userID uuid.UUID, workspace string, requested utils.LintRuleSet) utils.LintRuleSet {
+	return s.LintPolicy.RulesFor(workspace, userID.String()).With(requested)
+}
+
+// lintOptions adds the glossary and readability limits of the destination
+// team and the author, and the platform's capabilities, to the rule sets
+// from lintRules. An empty platform skips the compatibility checks.
+func (s *ManuscriptService) lintOptions(userID uuid.UUID, workspace, platform string, requested utils.LintRuleSet) utils.LintOptions {
+	opts := utils.LintOptions{
+		Rules:       s.lintRules(userID, workspace, requested),
+		Glossary:    s.LintPolicy.GlossaryFor(workspace, userID.String()),
+		Dictionary:  s.LintPolicy.DictionaryFor(workspace, userID.String()),
+		Readability: s.LintPolicy.ReadabilityFor(workspace, userID.String()),
+	}
+	if capabilities, ok := s.Platforms[platform]; ok {
+		opts.Platform = &capabilities
+	}
+	return opts
+}
+
+// lintFailures returns a failed entry per destination, and ErrLintFailed,
+// if lint blocks any of them. Like strict mode, one blocked destination
+// stops them all so the draft never lands in only some workspaces.
+func lintFailures(destinations []petrelmodels.ValidatedDestination, results []destinationLint) ([]petrelmodels.DraftResultEntry, error) {
+	blocked := 0
+	for _, result := range results {
+		if result.blocking > 0 {
+			blocked++
+		}
+	}
+	if blocked == 0 {
+		return nil, nil
+	}
+
+	drafts := make([]petrelmodels.DraftResultEntry, len(destinations))
+	for i, destination := range destinations {
+		drafts[i] = petrelmodels.DraftResultEntry{
+			Platform:     "notion",
+			WorkspaceID:  destination.Workspace,
+			Status:       "fail",
+			LintWarnings: results[i].warnings,
+		}
+		if n := results[i].blocking; n > 0 {
+			drafts[i].ErrorMessage = fmt.Sprintf("%s: %d finding(s)", ErrLintFailed, n)
+		} else {
+			drafts[i].ErrorMessage = "not staged: lint failed for another destination"
+		}
+	}
+	return drafts, fmt.Errorf("%w in %d destination(s)", ErrLintFailed, blocked)
+}
+
+// withLintWarnings puts each destination's lint findings ahead of the
+// mapping warnings already on its entry. Entries follow destination order.
+func withLintWarnings(drafts []petrelmodels.DraftResultEntry, results []destinationLint) {
+	for i := range drafts {
+		if i >= len(results) || len(results[i].warnings) == 0 {
+			continue
+		}
+		drafts[i].LintWarnings = append(append([]utils.LintWarning(nil), results[i].warnings...), drafts[i].LintWarnings...)
+	}
+}
+
+// withMetrics sets the draft's metrics on every entry and returns them.
+func withMetrics(drafts []petrelmodels.DraftResultEntry, metrics *utils.DraftMetrics) []petrelmodels.DraftResultEntry {
+	for i := range drafts {
+		drafts[i].Metrics = metrics
+	}
+	return drafts
+}
+
+// Lint lints Markdown under the same rule sets as StageDraft without
+// staging it, and applies the fixes it can.
+func (s *ManuscriptService) Lint(ctx context.Context, userID uuid.UUID, req petrelmodels.LintRequest) (petrelmodels.LintResponse, error) {
+	doc, source, err := s.Parser.Parse(req.Markdown)
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrInvalidMarkdown, err)
+		logger.With(ctx).Error("markdown validation failed", zap.Error(err))
+		return petrelmodels.LintResponse{}, err
+	}
+
+	if _, ok := s.Platforms[req.Platform]; req.Platform != "" && !ok {
+		err := fmt.Errorf("%w: unknown platform %q", utils.ErrInvalidLintRules, req.Platform)
+		logger.With(ctx).Error("linting markdown failed", zap.Error(err))
+		return petrelmodels.LintResponse{}, err
+	}
+	warnings, err := s.Linter.Lint(doc, source, s.lintOptions(userID, req.WorkspaceID, req.Platform, req.LintRules))
+	if err != nil {
+		logger.With(ctx).Error("linting markdown failed", zap.Error(err))
+		return petrelmodels.LintResponse{}, err
+	}
+
+	// Redactions the policy would make anyway count as fixes; the rest are only suggested
+	screening := s.screenSensitive(doc, source)
+	fixed, applied := utils.ApplyFixes(source, append(warnings, screening.warnings(utils.SensitiveRedact)...))
+	warnings = append(warnings, screening.warnings()...)
+	if warnings == nil {
+		warnings = []utils.LintWarning{} // an empty list, not null, when the Markdown is clean
+	}
+	logger.With(ctx).Info("Linted markdown", zap.Int("warnings", len(warnings)), zap.Int("fixes", applied))
+	return petrelmodels.LintResponse{
+		Warnings:      warnings,
+		FixedMarkdown: string(fixed),
+		FixesApplied:  applied,
+		Metrics:       utils.ComputeDraftMetrics(doc, source),
+	}, nil
+}
+
+// ErrSensitiveContent is returned when the sensitive content policy blocks
+// a draft that holds personal data or secrets.
+var ErrSensitiveContent = errors.New("draft contains sensitive content blocked by policy")
+
+// screenedFinding is a sensitive finding with what the policy does about it.
+type screenedFinding struct {
+	finding utils.SensitiveFinding
+	action  utils.SensitiveAction
+	warning utils.LintWarning
+	inTitle bool // found in the request's title rather than the source
+}
+
+type sensitiveScreening []screenedFinding
+
+func (s *ManuscriptService) screenSensitive(doc ast.Node, source []byte) sensitiveScreening {
+	if s.Detector == nil {
+		return nil
+	}
+	var screening sensitiveScreening
+	for _, f := range s.Detector.Detect(doc, source) {
+		screening = append(screening, s.screenFinding(f, "", source))
+	}
+	return screening
+}
+
+// screenTitle screens a title sent with the request. The title is not in
+// the source, so its warnings have no line and no fix.
+func (s *ManuscriptService) screenTitle(title string) sensitiveScreening {
+	if s.Detector == nil || title == "" {
+		return nil
+	}
+	var screening sensitiveScreening
+	for _, f := range s.Detector.DetectText(title) {
+		sf := s.screenFinding(f, " in the title", []byte(title))
+		sf.inTitle = true
+		sf.warning.Line, sf.warning.Column, sf.warning.Fix = 0, 0, nil
+		screening = append(screening, sf)
+	}
+	return screening
+}
+
+func (s *ManuscriptService) screenFinding(f utils.SensitiveFinding, where string, source []byte) screenedFinding {
+	action := s.SensitivePolicy.ActionFor(f.Kind)
+	var w utils.LintWarning
+	switch action {
+	case utils.SensitiveBlock:
+		w = f.LintWarning(utils.SeverityError, fmt.Sprintf("Possible %s%s; blocked by policy", f.Kind, where), source)
+	case utils.SensitiveRedact:
+		w = f.LintWarning(utils.SeverityInfo, fmt.Sprintf("Possible %s%s; redacted before staging", f.Kind, where), source)
+	default:
+		w = f.LintWarning(utils.SeverityWarning, fmt.Sprintf("Possible %s%s", f.Kind, where), source)
+	}
+	return screenedFinding{finding: f, action: action, warning: w}
+}
+
+// redactTitle redacts what the policy redacts from a draft's title.
+func (s *ManuscriptService) redactTitle(title string) string {
+	var findings []utils.SensitiveFinding
+	for _, sf := range s.screenTitle(title) {
+		if sf.action == utils.SensitiveRedact {
+			findings = append(findings, sf.finding)
+		}
+	}
+	return string(utils.RedactSensitive([]byte(title), findings))
+}
+
+// warnings returns the findings as lint warnings, only those with the
+// given actions if any are given.
+func (s sensitiveScreening) warnings(actions ...utils.SensitiveAction) []utils.LintWarning {
+	var warnings []utils.LintWarning
+	for _, sf := range s {
+		if len(actions) == 0 || slices.Contains(actions, sf.action) {
+			warnings = append(warnings, sf.warning)
+		}
+	}
+	return warnings
+}
+
+// findings returns the findings in the source with the given action.
+func (s sensitiveScreening) findings(action utils.SensitiveAction) []utils.SensitiveFinding {
+	var findings []utils.SensitiveFinding
+	for _, sf := range s {
+		if sf.action == action && !sf.inTitle {
+			findings = append(findings, sf.finding)
+		}
+	}
+	return findings
+}
+
+// count returns how many findings have the given action, the title's included.
+func (s sensitiveScreening) count(action utils.SensitiveAction) int {
+	n := 0
+	for _, sf := range s {
+		if sf.action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// sensitiveFailures returns a failed entry per destination carrying its
+// lint warnings, which include the blocking findings.
+func sensitiveFailures(destinations []petrelmodels.ValidatedDestination, results []destinationLint, err error) []petrelmodels.DraftResultEntry {
+	drafts := make([]petrelmodels.DraftResultEntry, len(destinations))
+	for i, destination := range destinations {
+		drafts[i] = petrelmodels.DraftResultEntry{
+			Platform:     "notion",
+			WorkspaceID:  destination.Workspace,
+			Status:       "fail",
+			ErrorMessage: err.Error(),
+			LintWarnings: results[i].warnings,
+		}
+	}
+	return drafts
+}
+
+// stagingStatus summarises a failed staging run: "partial_success" if some
+// destination still received its draft, "fail" otherwise.
+func stagingStatus(drafts []petrelmodels.DraftResultEntry) string {
+	for _, draft := range drafts {
+		if draft.Status != "fail" {
+			return "partial_success"
+		}
+	}
+	return "fail"
+}
+
+// applyFrontMatter fills in request fields the caller left empty from the
+// markdown's front matter. Fields set in the request always win.
+func applyFrontMatter(req *petrelmodels.CreateDraftRequest, fm *utils.FrontMatter) {
+	if req.Title == "" {
+		req.Title = fm.Title
+	}
+	if len(req.Destinations) == 0 {
+		for _, dest := range fm.Destinations {
+			req.Destinations = append(req.Destinations, petrelmodels.DraftDestination{
+				Platform:    dest.Platform,
+				WorkspaceID: dest.WorkspaceID,
+				Append:      dest.Append,
+				PageID:      dest.PageID,
+			})
+		}
+	}
+
+	if fm.Source == "" && len(fm.Tags) == 0 && fm.PublishAt == nil {
+		return
+	}
+	if req.Metadata == nil {
+		req.Metadata = &petrelmodels.DraftMetadata{}
+	}
+	if req.Metadata.Source == "" {
+		req.Metadata.Source = fm.Source
+	}
+	if len(req.Metadata.Tags) == 0 {
+		req.Metadata.Tags = fm.Tags
+	}
+	if req.Metadata.PublishAt == nil {
+		req.Metadata.PublishAt = fm.PublishAt
+	}
+}
+
+func missingRequestFields(req petrelmodels.CreateDraftRequest) []string {
+	var missing []string
+	if req.Title == "" {
+		missing = append(missing, "title")
+	}
+	if len(req.Destinations) == 0 {
+		missing = append(missing, "destinations")
+	}
+	return missing
+}
@@ -0,0 +1,86 @@
+package notion
+
+import (
+	"context"
+	"github.com/jomei/notionapi"
+	"github.com/yuin/goldmark/ast"
+	"regexp"
+	"strings"
+)
+
+// admonition describes how a GitHub-style alert renders as a Notion callout.
+type admonition struct {
+	emoji string
+	color notionapi.Color
+}
+
+var admonitions = map[string]admonition{
+	"NOTE":      {emoji: "ℹ️", color: notionapi.ColorBlueBackground},
+	"TIP":       {emoji: "💡", color: notionapi.ColorGreenBackground},
+	"IMPORTANT": {emoji: "❗", color: notionapi.ColorPurpleBackground},
+	"WARNING":   {emoji: "⚠️", color: notionapi.ColorYellowBackground},
+	"CAUTION":   {emoji: "🛑", color: notionapi.ColorRedBackground},
+}
+
+var admonitionMarkerRe = regexp.MustCompile(`^\[!([A-Za-z]+)\]`)
+
+// admonitionKind reports whether a blockquote opens with a `[!NOTE]`-style
+// marker on its first line and returns the normalised marker name.
+func admonitionKind(node ast.Node, source []byte) (string, bool) {
+	first := node.FirstChild()
+	if first == nil || first.Type() != ast.TypeBlock || first.Lines().Len() == 0 {
+		return "", false
+	}
+	line := first.Lines().At(0)
+	match := admonitionMarkerRe.FindSubmatch(line.Value(source))
+	if match == nil {
+		return "", false
+	}
+	kind := strings.ToUpper(string(match[1]))
+	if _, ok := admonitions[kind]; !ok {
+		return "", false
+	}
+	return kind, true
+}
+
+func mapCallout(ctx context.Context, node ast.Node, source []byte, ctxMap *MappingContext, kind string) error {
+	style := admonitions[kind]
+	richText := ctxMap.LeadText(ctx, node, source)
+	richText = trimLeadingSpace(trimLeadingText(richText, len("[!"+kind+"]")))
+
+	emoji := notionapi.Emoji(style.emoji)
+	block := &notionapi.CalloutBlock{
+		BasicBlock: notionapi.BasicBlock{
+			Type:   notionapi.BlockTypeCallout,
+			Object: notionapi.ObjectTypeBlock,
+		},
+		Callout: notionapi.Callout{
+			RichText: richText,
+			Icon: &notionapi.Icon{
+				Type:  "emoji",
+				Emoji: &emoji,
+			},
+			Color: style.color.String(),
+		},
+	}
+
+	b := &BlockWithChildren{Block: block}
+	ctxMap.AddBlock(ctx, b)
+
+	ctxMap.PushParent(ctx, node, b)
+	return nil
+}
+
+func newToggleBlock(richText []notionapi.RichText) *BlockWithChildren {
+	return &BlockWithChildren{
+		Block: &notionapi.ToggleBlock{
+			BasicBlock: notionapi.BasicBlock{
+				Type:   notionapi.BlockTypeToggle,
+				Object: notionapi.ObjectTypeBlock,
+			},
+			Toggle: notionapi.Toggle{
+				RichText: richText,
+			},
+		},
+	}
+}
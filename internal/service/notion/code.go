@@ -0,0 +1,194 @@
+package notion
+
+import (
+	"strings"
+)
+
+// plainTextLanguage is Notion's name for code without syntax highlighting.
+const plainTextLanguage = "plain text"
+
+// notionLanguages is the fixed list of code languages the Notion API accepts.
+var notionLanguages = map[string]bool{
+	"abap": true, "arduino": true, "bash": true, "basic": true, "c": true, "clojure": true,
+	"coffeescript": true, "c++": true, "c#": true, "css": true, "dart": true, "diff": true,
+	"docker": true, "elixir": true, "elm": true, "erlang": true, "flow": true, "fortran": true,
+	"f#": true, "gherkin": true, "glsl": true, "go": true, "graphql": true, "groovy": true,
+	"haskell": true, "html": true, "java": true, "javascript": true, "json": true, "julia": true,
+	"kotlin": true, "latex": true, "less": true, "lisp": true, "livescript": true, "lua": true,
+	"makefile": true, "markdown": true, "markup": true, "matlab": true, "mermaid": true,
+	"nix": true, "objective-c": true, "ocaml": true, "pascal": true, "perl": true, "php": true,
+	"plain text": true, "powershell": true, "prolog": true, "protobuf": true, "python": true,
+	"r": true, "reason": true, "ruby": true, "rust": true, "sass": true, "scala": true,
+	"scheme": true, "scss": true, "shell": true, "sql": true, "swift": true, "typescript": true,
+	"vb.net": true, "verilog": true, "vhdl": true, "visual basic": true, "webassembly": true,
+	"xml": true, "yaml": true, "java/c/c++/c#": true,
+}
+
+// languageAliases maps common fence identifiers to Notion language names.
+var languageAliases = map[string]string{
+	"js":            "javascript",
+	"jsx":           "javascript",
+	"mjs":           "javascript",
+	"cjs":           "javascript",
+	"node":          "javascript",
+	"ts":            "typescript",
+	"tsx":           "typescript",
+	"mts":           "typescript",
+	"sh":            "shell",
+	"zsh":           "shell",
+	"console":       "shell",
+	"shell-session": "shell",
+	"shellscript":   "shell",
+	"yml":           "yaml",
+	"golang":        "go",
+	"py":            "python",
+	"python3":       "python",
+	"py3":           "python",
+	"rb":            "ruby",
+	"rs":            "rust",
+	"cpp":           "c++",
+	"cxx":           "c++",
+	"cc":            "c++",
+	"hpp":           "c++",
+	"h":             "c",
+	"cs":            "c#",
+	"csharp":        "c#",
+	"fs":            "f#",
+	"fsharp":        "f#",
+	"kt":            "kotlin",
+	"kts":           "kotlin",
+	"md":            "markdown",
+	"tex":           "latex",
+	"dockerfile":    "docker",
+	"make":          "makefile",
+	"mk":            "makefile",
+	"ps1":           "powershell",
+	"pwsh":          "powershell",
+	"ps":            "powershell",
+	"proto":         "protobuf",
+	"objc":          "objective-c",
+	"objectivec":    "objective-c",
+	"vb":            "visual basic",
+	"vba":           "visual basic",
+	"vbnet":         "vb.net",
+	"wasm":          "webassembly",
+	"wat":           "webassembly",
+	"ex":            "elixir",
+	"exs":           "elixir",
+	"erl":           "erlang",
+	"hs":            "haskell",
+	"clj":           "clojure",
+	"cljs":          "clojure",
+	"coffee":        "coffeescript",
+	"pl":            "perl",
+	"jl":            "julia",
+	"ml":            "ocaml",
+	"scm":           "scheme",
+	"gql":           "graphql",
+	"patch":         "diff",
+	"htm":           "html",
+	"xhtml":         "html",
+	"svg":           "xml",
+	"jsonc":         "json",
+	"json5":         "json",
+	"postgresql":    "sql",
+	"mysql":         "sql",
+	"psql":          "sql",
+	"text":          "plain text",
+	"txt":           "plain text",
+	"plaintext":     "plain text",
+	"plain":         "plain text",
+}
+
+// fenceLanguages is the reverse of languageAliases for the Notion names
+// that cannot be written as a fence identifier as they are.
+var fenceLanguages = map[string]string{
+	"c++":           "cpp",
+	"c#":            "csharp",
+	"f#":            "fsharp",
+	"visual basic":  "vb",
+	"vb.net":        "vbnet",
+	"java/c/c++/c#": "java",
+	"plain text":    "",
+}
+
+// notionLanguage returns the Notion name for a fence language and whether
+// Notion knows it. Unknown languages fall back to plain text.
+func notionLanguage(language string) (string, bool) {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if language == "" {
+		return plainTextLanguage, true
+	}
+	if alias, ok := languageAliases[language]; ok {
+		return alias, true
+	}
+	if notionLanguages[language] {
+		return language, true
+	}
+	return plainTextLanguage, false
+}
+
+// fenceLanguage is the inverse of notionLanguage.
+func fenceLanguage(language string) string {
+	if fence, ok := fenceLanguages[language]; ok {
+		return fence
+	}
+	return language
+}
+
+// parseFenceInfo splits a fence info string such as `go {title="main.go"}`
+// into the language and its `{key=value}` attributes. Values may be quoted.
+func parseFenceInfo(info string) (string, map[string]string) {
+	info = strings.TrimSpace(info)
+	language := info
+	attrs := ""
+	if open := strings.IndexByte(info, '{'); open >= 0 {
+		language = info[:open]
+		attrs = info[open+1:]
+		if end := strings.LastIndexByte(attrs, '}'); end >= 0 {
+			attrs = attrs[:end]
+		}
+	}
+	if fields := strings.Fields(language); len(fields) > 0 {
+		language = fields[0]
+	} else {
+		language = ""
+	}
+	return language, parseFenceAttributes(attrs)
+}
+
+func parseFenceAttributes(s string) map[string]string {
+	attrs := make(map[string]string)
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimLeft(s, " \t,") {
+		eq := strings.IndexByte(s, '=')
+		sep := strings.IndexAny(s, " \t,")
+		if eq < 0 || (sep >= 0 && sep < eq) {
+			// A bare flag such as `{linenos}`
+			if sep < 0 {
+				sep = len(s)
+			}
+			attrs[strings.ToLower(s[:sep])] = ""
+			s = s[sep:]
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = s[eq+1:]
+		var value string
+		if len(s) > 0 && (s[0] == '"' || s[0] == '\'') {
+			end := strings.IndexByte(s[1:], s[0])
+			if end < 0 {
+				value, s = s[1:], ""
+			} else {
+				value, s = s[1:end+1], s[end+2:]
+			}
+		} else {
+			end := strings.IndexAny(s, " \t,")
+			if end < 0 {
+				end = len(s)
+			}
+			value, s = s[:end], s[end:]
+		}
+		attrs[key] = value
+	}
+	return attrs
+}
@@ -0,0 +1,88 @@
+package notion
+
+import (
+	"fmt"
+	"github.com/yuin/goldmark"
+	"sort"
+	"sync"
+)
+
+// Extension is a piece of custom Markdown syntax: the goldmark extension
+// that parses it and the mappers that turn its AST nodes into Notion blocks.
+// Extensions register themselves with RegisterExtension, usually from an
+// init function, and are switched on per deployment by name in the
+// markdown.extensions config.
+type Extension struct {
+	Name     string
+	Markdown goldmark.Extender
+	// RegisterMappers adds the extension's mappers, e.g. with mapper.Register.
+	RegisterMappers func(mapper *PetrelMarkdownToNotionMapper)
+	// SetChildren attaches children to the extension's own block types that
+	// hold other blocks. Column lists, columns and synced blocks are built in.
+	SetChildren ChildrenSetter
+}
+
+var (
+	extensionsMu sync.RWMutex
+	extensions   = make(map[string]Extension)
+)
+
+// RegisterExtension makes ext available to LoadExtensions. It panics if the
+// name is empty or already taken, as two extensions fighting over a name is
+// a build mistake.
+func RegisterExtension(ext Extension) {
+	extensionsMu.Lock()
+	defer extensionsMu.Unlock()
+
+	if ext.Name == "" {
+		panic("notion: extension registered without a name")
+	}
+	if _, dup := extensions[ext.Name]; dup {
+		panic("notion: extension " + ext.Name + " registered twice")
+	}
+	extensions[ext.Name] = ext
+}
+
+// LoadExtensions returns the registered extensions with the given names,
+// in order.
+func LoadExtensions(names []string) ([]Extension, error) {
+	extensionsMu.RLock()
+	defer extensionsMu.RUnlock()
+
+	loaded := make([]Extension, 0, len(names))
+	for _, name := range names {
+		ext, ok := extensions[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown markdown extension %q (registered: %v)", name, registeredExtensions())
+		}
+		loaded = append(loaded, ext)
+	}
+	return loaded, nil
+}
+
+func registeredExtensions() []string {
+	names := make([]string, 0, len(extensions))
+	for name := range extensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyExtensions registers the mappers and children setters of exts on
+// mapper and returns the goldmark extensions to parse their syntax with.
+func ApplyExtensions(mapper *PetrelMarkdownToNotionMapper, exts []Extension) []goldmark.Extender {
+	var extenders []goldmark.Extender
+	for _, ext := range exts {
+		if ext.RegisterMappers != nil {
+			ext.RegisterMappers(mapper)
+		}
+		if ext.SetChildren != nil {
+			mapper.RegisterChildren(ext.SetChildren)
+		}
+		if ext.Markdown != nil {
+			extenders = append(extenders, ext.Markdown)
+		}
+	}
+	return extenders
+}
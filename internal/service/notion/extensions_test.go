@@ -0,0 +1,224 @@
+package notion
+
+import (
+	"bytes"
+	"context"
+	"github.com/google/uuid"
+	"github.com/jomei/notionapi"
+	"github.com/obi2na/petrel/internal/logger"
+	petrelmodels "github.com/obi2na/petrel/internal/models"
+	utils "github.com/obi2na/petrel/internal/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+	"reflect"
+	"testing"
+)
+
+// syncedBlock is a test extension: a `!!! synced` line becomes a node that
+// maps to an empty Notion synced block.
+type syncedBlock struct {
+	ast.BaseBlock
+}
+
+var kindSyncedBlock = ast.NewNodeKind("SyncedBlock")
+
+func (n *syncedBlock) Kind() ast.NodeKind { return kindSyncedBlock }
+
+func (n *syncedBlock) Dump(source []byte, level int) { ast.DumpHelper(n, source, level, nil, nil) }
+
+type syncedBlockParser struct{}
+
+func (p *syncedBlockParser) Trigger() []byte { return []byte{'!'} }
+
+func (p *syncedBlockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	if !bytes.Equal(bytes.TrimSpace(line), []byte("!!! synced")) {
+		return nil, parser.NoChildren
+	}
+	node := &syncedBlock{}
+	node.Lines().Append(segment)
+	reader.Advance(segment.Len() - 1)
+	return node, parser.NoChildren
+}
+
+func (p *syncedBlockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	return parser.Close
+}
+
+func (p *syncedBlockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (p *syncedBlockParser) CanInterruptParagraph() bool { return true }
+
+func (p *syncedBlockParser) CanAcceptIndentedLine() bool { return false }
+
+type syncedExtension struct{}
+
+func (e *syncedExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithBlockParsers(util.Prioritized(&syncedBlockParser{}, 100)))
+}
+
+func mapSyncedBlock(ctx context.Context, node ast.Node, source []byte, mc *MappingContext) error {
+	mc.AddBlock(ctx, &BlockWithChildren{
+		Block: &notionapi.SyncedBlock{
+			BasicBlock: notionapi.BasicBlock{Object: notionapi.ObjectTypeBlock, Type: notionapi.BlockTypeSyncedBlock},
+		},
+		Children: []*BlockWithChildren{{Block: &notionapi.ParagraphBlock{
+			BasicBlock: notionapi.BasicBlock{Object: notionapi.ObjectTypeBlock, Type: notionapi.BlockTypeParagraph},
+			Paragraph:  notionapi.Paragraph{RichText: plainRichText("Synced content")},
+		}}},
+	})
+	return nil
+}
+
+func init() {
+	RegisterExtension(Extension{
+		Name:     "test-synced",
+		Markdown: &syncedExtension{},
+		RegisterMappers: func(mapper *PetrelMarkdownToNotionMapper) {
+			mapper.Register(&syncedBlock{}, mapSyncedBlock)
+		},
+	})
+}
+
+func TestExtensions_MapCustomBlocks(t *testing.T) {
+	logger.Init()
+	exts, err := LoadExtensions([]string{"test-synced"})
+	require.NoError(t, err)
+
+	mapper := NewPetrelMarkdownToNotionMapper()
+	mapper.RegisterMappers()
+	p := utils.NewDefaultMarkdownParser(ApplyExtensions(mapper, exts)...)
+
+	doc, source, err := p.Parse("Intro\n\n!!! synced\n\nOutro\n")
+	require.NoError(t, err)
+	blocks, report, err := mapper.Map(context.Background(), doc, source, MapOptions{})
+	require.NoError(t, err)
+
+	assert.True(t, report.Empty())
+	require.Len(t, blocks, 3)
+	assert.IsType(t, &notionapi.SyncedBlock{}, blocks[1].Block)
+}
+
+func TestExtensions_UnmappedCustomBlockIsDropped(t *testing.T) {
+	logger.Init()
+	mapper := NewPetrelMarkdownToNotionMapper()
+	mapper.RegisterMappers()
+	p := utils.NewDefaultMarkdownParser(&syncedExtension{})
+
+	doc, source, err := p.Parse("Intro\n\n!!! synced\n")
+	require.NoError(t, err)
+	blocks, report, err := mapper.Map(context.Background(), doc, source, MapOptions{})
+	require.NoError(t, err)
+
+	require.Len(t, blocks, 1)
+	assert.Equal(t, []petrelmodels.DroppedNode{
+		{Kind: "SyncedBlock", Line: 3, Reason: "no Notion mapper for this block"},
+	}, report.Dropped)
+}
+
+func TestExtensions_Capabilities(t *testing.T) {
+	mapper := NewPetrelMarkdownToNotionMapper()
+	mapper.RegisterMappers()
+	assert.False(t, mapper.Capabilities().Blocks[reflect.TypeOf(&syncedBlock{})])
+
+	exts, err := LoadExtensions([]string{"test-synced"})
+	require.NoError(t, err)
+	ApplyExtensions(mapper, exts)
+	capabilities := mapper.Capabilities()
+	assert.True(t, capabilities.Blocks[reflect.TypeOf(&syncedBlock{})])
+	assert.True(t, capabilities.Blocks[reflect.TypeOf(&ast.Heading{})])
+}
+
+func TestExtensions_StageBlockWithChildren(t *testing.T) {
+	logger.Init()
+	exts, err := LoadExtensions([]string{"test-synced"})
+	require.NoError(t, err)
+	mapper := NewPetrelMarkdownToNotionMapper()
+	mapper.RegisterMappers()
+	p := utils.NewDefaultMarkdownParser(ApplyExtensions(mapper, exts)...)
+	client := &fakeNotionClient{}
+	svc := NewNotionDraftService(client, mapper, nil, nil)
+
+	// The first synced block fits the create request; the nested one is
+	// too deep for it and is appended under its list item
+	doc, source, err := p.Parse("Intro\n\n!!! synced\n\n- one\n  - two\n    !!! synced\n")
+	require.NoError(t, err)
+	results, err := svc.StageDraft(context.Background(), uuid.New(), []petrelmodels.ValidatedDestination{{
+		UserIntegration: petrelmodels.UserIntegration{Token: "token", DraftsRepoID: "drafts-repo"},
+	}}, doc, source, petrelmodels.StageOptions{})
+	require.NoError(t, err)
+	assert.Nil(t, results[0].MappingReport)
+
+	require.NotNil(t, client.created)
+	require.Len(t, client.created.Children, 2)
+	synced := client.created.Children[1].(*notionapi.SyncedBlock)
+	require.Len(t, synced.SyncedBlock.Children, 1)
+
+	require.Len(t, client.appends, 2)
+	two := client.appends[1].children[0].(*notionapi.BulletedListItemBlock)
+	require.Len(t, two.BulletedListItem.Children, 1)
+	nested := two.BulletedListItem.Children[0].(*notionapi.SyncedBlock)
+	require.Len(t, nested.SyncedBlock.Children, 1)
+	assert.Equal(t, "Synced content", nested.SyncedBlock.Children[0].(*notionapi.ParagraphBlock).Paragraph.RichText[0].Text.Content)
+}
+
+// groupBlock stands in for a custom Notion block type that holds blocks.
+type groupBlock struct {
+	notionapi.BasicBlock
+	children []notionapi.Block
+}
+
+func TestExtensions_SetChildren(t *testing.T) {
+	mapper := NewPetrelMarkdownToNotionMapper()
+	mapper.RegisterMappers()
+	children := []notionapi.Block{&notionapi.DividerBlock{}}
+	assert.False(t, mapper.SetChildren(&groupBlock{}, children))
+
+	ApplyExtensions(mapper, []Extension{{
+		Name: "test-group",
+		SetChildren: func(block notionapi.Block, children []notionapi.Block) bool {
+			group, ok := block.(*groupBlock)
+			if ok {
+				group.children = children
+			}
+			return ok
+		},
+	}})
+	group := &groupBlock{}
+	assert.True(t, mapper.SetChildren(group, children))
+	assert.Equal(t, children, group.children)
+	assert.True(t, mapper.SetChildren(&notionapi.ColumnListBlock{}, children))
+}
+
+func TestMap_ReportsChildrenOfBlocksThatCannotHoldThem(t *testing.T) {
+	logger.Init()
+	mapper := NewPetrelMarkdownToNotionMapper()
+	mapper.RegisterMappers()
+	mapper.Register(&ast.Blockquote{}, func(ctx context.Context, node ast.Node, source []byte, mc *MappingContext) error {
+		divider := newDividerBlock()
+		mc.AddBlock(ctx, divider)
+		mc.PushParent(ctx, node, divider)
+		return nil
+	})
+
+	doc, source, err := newTestParser().Parse("Intro\n\n> one\n>\n> two\n")
+	require.NoError(t, err)
+	blocks, report, err := mapper.Map(context.Background(), doc, source, MapOptions{})
+	require.NoError(t, err)
+
+	require.Len(t, blocks, 2)
+	assert.Empty(t, blocks[1].Children)
+	assert.Equal(t, []petrelmodels.DroppedNode{
+		{Kind: "Blockquote", Line: 3, Reason: "Notion cannot nest blocks in a divider block; 2 block(s) dropped"},
+	}, report.Dropped)
+}
+
+func TestLoadExtensions_Unknown(t *testing.T) {
+	_, err := LoadExtensions([]string{"columns"})
+	assert.ErrorContains(t, err, `unknown markdown extension "columns"`)
+}
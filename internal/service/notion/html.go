@@ -0,0 +1,306 @@
+package notion
+
+import (
+	"context"
+	"fmt"
+	"github.com/jomei/notionapi"
+	"golang.org/x/net/html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Raw HTML policy: simple formatting tags, paragraphs, line breaks, rules,
+// images and <details>/<summary> sections are converted to their Notion
+// equivalents. Every other tag is stripped (its text is kept) and reported,
+// and script/style content is dropped entirely.
+
+// htmlTag is a single parsed HTML tag.
+type htmlTag struct {
+	name    string
+	closing bool
+	attrs   map[string]string
+}
+
+// inlineHTMLTags are the inline tags that map onto Notion annotations.
+var inlineHTMLTags = map[string]bool{
+	"b": true, "strong": true,
+	"i": true, "em": true,
+	"s": true, "del": true, "strike": true,
+	"u": true, "ins": true,
+	"code": true,
+	"a":    true,
+	"br":   true,
+}
+
+// paragraphHTMLTags only separate paragraphs and carry no formatting.
+var paragraphHTMLTags = map[string]bool{
+	"p": true, "div": true,
+}
+
+// blockHTMLTags are stripped, but still end the current paragraph so their
+// text does not run into the next element.
+var blockHTMLTags = map[string]bool{
+	"section": true, "article": true, "header": true, "footer": true, "aside": true, "nav": true,
+	"ul": true, "ol": true, "li": true, "dl": true, "dt": true, "dd": true,
+	"table": true, "thead": true, "tbody": true, "tr": true, "th": true, "td": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"blockquote": true, "pre": true, "figure": true, "figcaption": true,
+}
+
+// convertedHTMLTags are the tags an HTML block keeps in some form: as
+// formatting, paragraphs, toggles, dividers or images.
+func convertedHTMLTags() map[string]bool {
+	tags := map[string]bool{"details": true, "summary": true, "hr": true, "img": true}
+	for _, set := range []map[string]bool{inlineHTMLTags, paragraphHTMLTags} {
+		for tag := range set {
+			tags[tag] = true
+		}
+	}
+	return tags
+}
+
+var htmlWhitespaceRe = regexp.MustCompile(`\s+`)
+
+func newHTMLTag(tok html.Token, closing bool) htmlTag {
+	tag := htmlTag{
+		name:    strings.ToLower(tok.Data),
+		closing: closing,
+		attrs:   make(map[string]string, len(tok.Attr)),
+	}
+	for _, attr := range tok.Attr {
+		tag.attrs[strings.ToLower(attr.Key)] = attr.Val
+	}
+	return tag
+}
+
+// parseInlineTag parses a single raw inline HTML tag and reports whether it
+// is one of the tags the mapper can convert.
+func parseInlineTag(raw string) (htmlTag, bool) {
+	z := html.NewTokenizer(strings.NewReader(raw))
+	switch tt := z.Next(); tt {
+	case html.StartTagToken, html.SelfClosingTagToken:
+		tag := newHTMLTag(z.Token(), false)
+		return tag, inlineHTMLTags[tag.name]
+	case html.EndTagToken:
+		tag := newHTMLTag(z.Token(), true)
+		return tag, inlineHTMLTags[tag.name]
+	}
+	return htmlTag{}, false
+}
+
+func applyHTMLTag(style inlineStyle, tag htmlTag) inlineStyle {
+	switch tag.name {
+	case "b", "strong":
+		style.bold = true
+	case "i", "em":
+		style.italic = true
+	case "s", "del", "strike":
+		style.strikethrough = true
+	case "u", "ins":
+		style.underline = true
+	case "code":
+		style.code = true
+	case "a":
+		if href := tag.attrs["href"]; href != "" {
+			style.link = href
+		}
+	}
+	return style
+}
+
+func isAbsoluteURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+type styledTag struct {
+	name  string
+	style inlineStyle
+}
+
+// htmlConverter turns a raw HTML block into Notion blocks, adding them to
+// the mapping context as it goes.
+type htmlConverter struct {
+	ctx       context.Context
+	mc        *MappingContext
+	text      *richTextBuilder
+	styles    []styledTag
+	skipDepth int
+	summary   *notionapi.ToggleBlock
+	dropped   []string
+	seen      map[string]bool
+}
+
+// convertHTMLBlock applies the raw HTML policy to an HTML block and returns
+// a description of everything that was dropped.
+//
+// A <details> section usually spans several HTML blocks with Markdown in
+// between, so the toggle it opens stays the current parent of mc until a
+// later </details> closes it.
+func convertHTMLBlock(ctx context.Context, raw string, mc *MappingContext) []string {
+	c := &htmlConverter{
+		ctx:  ctx,
+		mc:   mc,
+		text: &richTextBuilder{},
+		seen: make(map[string]bool),
+	}
+
+	z := html.NewTokenizer(strings.NewReader(raw))
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if c.summary != nil {
+				// Keep the text as the toggle's title rather than lose it
+				c.report("unclosed <summary>")
+				c.endSummary()
+			}
+			c.flush()
+			return c.dropped
+		case html.TextToken:
+			if c.skipDepth == 0 {
+				c.text.write(htmlWhitespaceRe.ReplaceAllString(string(z.Text()), " "), c.style())
+			}
+		case html.CommentToken:
+			c.report("HTML comment stripped")
+		case html.StartTagToken, html.SelfClosingTagToken:
+			c.open(newHTMLTag(z.Token(), false), tt == html.SelfClosingTagToken)
+		case html.EndTagToken:
+			c.close(newHTMLTag(z.Token(), true))
+		}
+	}
+}
+
+func (c *htmlConverter) style() inlineStyle {
+	if n := len(c.styles); n > 0 {
+		return c.styles[n-1].style
+	}
+	return inlineStyle{}
+}
+
+func (c *htmlConverter) report(reason string) {
+	if c.seen[reason] {
+		return
+	}
+	c.seen[reason] = true
+	c.dropped = append(c.dropped, reason)
+}
+
+func (c *htmlConverter) open(tag htmlTag, selfClosing bool) {
+	switch {
+	case tag.name == "script" || tag.name == "style":
+		c.report(fmt.Sprintf("<%s> content dropped", tag.name))
+		if !selfClosing {
+			c.skipDepth++
+		}
+	case tag.name == "br":
+		c.text.write("\n", c.style())
+	case tag.name == "details":
+		c.flush()
+		toggle := newToggleBlock(plainRichText("Details"))
+		c.mc.AddBlock(c.ctx, toggle)
+		c.mc.PushParent(c.ctx, nil, toggle)
+		c.mc.openToggles++
+	case tag.name == "summary":
+		c.flush()
+		if parent := c.mc.currentParent; parent != nil {
+			c.summary, _ = parent.Block.(*notionapi.ToggleBlock)
+		}
+	case tag.name == "hr":
+		c.flush()
+		c.mc.AddBlock(c.ctx, newDividerBlock())
+	case tag.name == "img":
+		c.flush()
+		src := tag.attrs["src"]
+		if !isAbsoluteURL(src) {
+			c.report(fmt.Sprintf("<img> %q has no absolute URL", src))
+			return
+		}
+		c.mc.AddBlock(c.ctx, newImageBlock(src, plainCaption(tag.attrs["alt"])))
+	case inlineHTMLTags[tag.name]:
+		if !selfClosing {
+			c.styles = append(c.styles, styledTag{name: tag.name, style: applyHTMLTag(c.style(), tag)})
+		}
+	case paragraphHTMLTags[tag.name]:
+		c.flush()
+	case blockHTMLTags[tag.name]:
+		c.flush()
+		c.report(fmt.Sprintf("<%s> stripped", tag.name))
+	default:
+		c.report(fmt.Sprintf("<%s> stripped", tag.name))
+	}
+}
+
+func (c *htmlConverter) close(tag htmlTag) {
+	switch {
+	case tag.name == "script" || tag.name == "style":
+		if c.skipDepth > 0 {
+			c.skipDepth--
+		}
+	case tag.name == "summary":
+		c.endSummary()
+	case tag.name == "details":
+		c.flush()
+		if c.mc.openToggles == 0 {
+			c.report("</details> without matching <details> ignored")
+			return
+		}
+		c.mc.closeToggle()
+	case inlineHTMLTags[tag.name]:
+		for i := len(c.styles) - 1; i >= 0; i-- {
+			if c.styles[i].name == tag.name {
+				c.styles = c.styles[:i]
+				break
+			}
+		}
+	case paragraphHTMLTags[tag.name], blockHTMLTags[tag.name]:
+		c.flush()
+	}
+}
+
+// endSummary makes the text collected since <summary> the title of the
+// toggle it belongs to.
+func (c *htmlConverter) endSummary() {
+	if c.summary != nil {
+		if richText := trimTrailingSpace(trimLeadingSpace(c.text.result())); len(richText) > 0 {
+			c.summary.Toggle.RichText = richText
+		}
+		c.summary = nil
+	}
+	c.text = &richTextBuilder{}
+}
+
+// flush closes the paragraph being built, if it has any visible text.
+func (c *htmlConverter) flush() {
+	if c.summary != nil {
+		// Summary text is collected until </summary>
+		return
+	}
+	segments := trimTrailingSpace(trimLeadingSpace(c.text.result()))
+	c.text = &richTextBuilder{}
+	if len(segments) == 0 {
+		return
+	}
+	c.mc.AddBlock(c.ctx, &BlockWithChildren{
+		Block: &notionapi.ParagraphBlock{
+			BasicBlock: notionapi.BasicBlock{
+				Object: notionapi.ObjectTypeBlock,
+				Type:   notionapi.BlockTypeParagraph,
+			},
+			Paragraph: notionapi.Paragraph{
+				RichText: segments,
+			},
+		},
+	})
+}
+
+func plainCaption(text string) []notionapi.RichText {
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	return plainRichText(text)
+}
@@ -0,0 +1,213 @@
+package notion
+
+import (
+	"fmt"
+	"github.com/jomei/notionapi"
+	"github.com/obi2na/petrel/config"
+	"github.com/yuin/goldmark/ast"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// LinkBlock is the kind of Notion block a standalone link becomes.
+type LinkBlock string
+
+const (
+	LinkBlockBookmark  LinkBlock = "bookmark"
+	LinkBlockEmbed     LinkBlock = "embed"
+	LinkBlockVideo     LinkBlock = "video"
+	LinkBlockParagraph LinkBlock = "paragraph" // keep the link as text
+)
+
+// LinkRule sends links on Host (or any of its subdomains), or links to
+// files with Extension, to Block. A rule with both must match both.
+type LinkRule struct {
+	Host      string
+	Extension string
+	Block     LinkBlock
+}
+
+func (r LinkRule) matches(u *url.URL) bool {
+	if r.Host == "" && r.Extension == "" {
+		return false
+	}
+	if r.Host != "" {
+		host := strings.ToLower(u.Hostname())
+		want := strings.ToLower(r.Host)
+		if host != want && !strings.HasSuffix(host, "."+want) {
+			return false
+		}
+	}
+	if r.Extension != "" && !strings.EqualFold(path.Ext(u.Path), r.Extension) {
+		return false
+	}
+	return true
+}
+
+// LinkRules decides which block a standalone link becomes. The first
+// matching rule wins; links that match none get Fallback.
+type LinkRules struct {
+	Rules    []LinkRule
+	Fallback LinkBlock
+}
+
+// BlockFor returns the block for a standalone link to raw.
+func (r *LinkRules) BlockFor(raw string) LinkBlock {
+	u, err := url.Parse(raw)
+	if err != nil || !isAbsoluteURL(raw) {
+		return LinkBlockParagraph
+	}
+	for _, rule := range r.Rules {
+		if rule.matches(u) {
+			return rule.Block
+		}
+	}
+	if r.Fallback == "" {
+		return LinkBlockBookmark
+	}
+	return r.Fallback
+}
+
+// DefaultLinkRules embeds the video and document hosts Notion can preview
+// and bookmarks everything else.
+var DefaultLinkRules = LinkRules{
+	Rules: []LinkRule{
+		{Host: "youtube.com", Block: LinkBlockVideo},
+		{Host: "youtu.be", Block: LinkBlockVideo},
+		{Host: "vimeo.com", Block: LinkBlockVideo},
+		{Host: "loom.com", Block: LinkBlockVideo},
+		{Extension: ".mp4", Block: LinkBlockVideo},
+		{Extension: ".mov", Block: LinkBlockVideo},
+		{Extension: ".webm", Block: LinkBlockVideo},
+		{Host: "figma.com", Block: LinkBlockEmbed},
+		{Host: "miro.com", Block: LinkBlockEmbed},
+		{Host: "codepen.io", Block: LinkBlockEmbed},
+		{Host: "gist.github.com", Block: LinkBlockEmbed},
+		{Host: "docs.google.com", Block: LinkBlockEmbed},
+		{Host: "drive.google.com", Block: LinkBlockEmbed},
+		{Extension: ".pdf", Block: LinkBlockEmbed},
+	},
+	Fallback: LinkBlockBookmark,
+}
+
+// LinkRuleSet holds the link rules of every team. Teams are keyed by their
+// Notion workspace ID.
+type LinkRuleSet struct {
+	defaults LinkRules
+	teams    map[string]LinkRules
+}
+
+// NewLinkRuleSet builds the rule set from config. Configured rules are
+// checked before the built-in DefaultLinkRules, and a team's own rules
+// before those.
+func NewLinkRuleSet(cfg config.LinkPreviewConfig) (*LinkRuleSet, error) {
+	defaults, err := linkRulesFromConfig(cfg.Rules, cfg.Fallback, DefaultLinkRules)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &LinkRuleSet{defaults: defaults, teams: make(map[string]LinkRules)}
+	for workspace, team := range cfg.Teams {
+		rules, err := linkRulesFromConfig(team.Rules, team.Fallback, defaults)
+		if err != nil {
+			return nil, fmt.Errorf("link previews for team %s: %w", workspace, err)
+		}
+		set.teams[strings.ToLower(workspace)] = rules
+	}
+	return set, nil
+}
+
+// ForWorkspace returns the rules for the team that owns workspace.
+func (s *LinkRuleSet) ForWorkspace(workspace string) *LinkRules {
+	if s == nil {
+		return &DefaultLinkRules
+	}
+	if rules, ok := s.teams[strings.ToLower(workspace)]; ok {
+		return &rules
+	}
+	return &s.defaults
+}
+
+func linkRulesFromConfig(rules []config.LinkPreviewRule, fallback string, base LinkRules) (LinkRules, error) {
+	out := LinkRules{Fallback: base.Fallback}
+	for _, r := range rules {
+		block, err := parseLinkBlock(r.Block)
+		if err != nil {
+			return LinkRules{}, err
+		}
+		if r.Host == "" && r.Extension == "" {
+			return LinkRules{}, fmt.Errorf("link preview rule for %q needs a host or an extension", r.Block)
+		}
+		out.Rules = append(out.Rules, LinkRule{Host: r.Host, Extension: r.Extension, Block: block})
+	}
+	out.Rules = append(out.Rules, base.Rules...)
+
+	if fallback != "" {
+		block, err := parseLinkBlock(fallback)
+		if err != nil {
+			return LinkRules{}, err
+		}
+		out.Fallback = block
+	}
+	return out, nil
+}
+
+func parseLinkBlock(s string) (LinkBlock, error) {
+	switch block := LinkBlock(strings.ToLower(s)); block {
+	case LinkBlockBookmark, LinkBlockEmbed, LinkBlockVideo, LinkBlockParagraph:
+		return block, nil
+	default:
+		return "", fmt.Errorf("unknown link preview block %q", s)
+	}
+}
+
+// standaloneLink returns the URL of a paragraph that holds nothing but one
+// link whose text is its own URL, such as a bare or `<…>` autolink.
+func standaloneLink(node ast.Node, source []byte) (string, bool) {
+	var link string
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		switch v := child.(type) {
+		case *ast.AutoLink:
+			if v.AutoLinkType != ast.AutoLinkURL || link != "" {
+				return "", false
+			}
+			link = string(v.URL(source))
+		case *ast.Link:
+			if link != "" || extractText(v, source) != string(v.Destination) {
+				return "", false
+			}
+			link = string(v.Destination)
+		case *ast.Text:
+			if strings.TrimSpace(string(v.Segment.Value(source))) != "" {
+				return "", false
+			}
+		default:
+			return "", false
+		}
+	}
+	return link, link != "" && isAbsoluteURL(link)
+}
+
+func newLinkBlock(block LinkBlock, link string) *BlockWithChildren {
+	switch block {
+	case LinkBlockEmbed:
+		return &BlockWithChildren{Block: &notionapi.EmbedBlock{
+			BasicBlock: notionapi.BasicBlock{Type: notionapi.BlockTypeEmbed, Object: notionapi.ObjectTypeBlock},
+			Embed:      notionapi.Embed{URL: link},
+		}}
+	case LinkBlockVideo:
+		return &BlockWithChildren{Block: &notionapi.VideoBlock{
+			BasicBlock: notionapi.BasicBlock{Type: notionapi.BlockTypeVideo, Object: notionapi.ObjectTypeBlock},
+			Video: notionapi.Video{
+				Type:     notionapi.FileTypeExternal,
+				External: &notionapi.FileObject{URL: link},
+			},
+		}}
+	default:
+		return &BlockWithChildren{Block: &notionapi.BookmarkBlock{
+			BasicBlock: notionapi.BasicBlock{Type: notionapi.BlockTypeBookmark, Object: notionapi.ObjectTypeBlock},
+			Bookmark:   notionapi.Bookmark{URL: link},
+		}}
+	}
+}
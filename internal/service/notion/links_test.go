@@ -0,0 +1,87 @@
+package notion
+
+import (
+	"github.com/jomei/notionapi"
+	"github.com/obi2na/petrel/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestLinkRules_BlockFor(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected LinkBlock
+	}{
+		{"https://www.youtube.com/watch?v=abc", LinkBlockVideo},
+		{"https://cdn.example.com/demo.MP4", LinkBlockVideo},
+		{"https://www.figma.com/file/abc", LinkBlockEmbed},
+		{"https://example.com/paper.pdf", LinkBlockEmbed},
+		{"https://notyoutube.com/watch", LinkBlockBookmark},
+		{"https://example.com/research", LinkBlockBookmark},
+		{"mailto:jane@example.com", LinkBlockParagraph},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			assert.Equal(t, tt.expected, DefaultLinkRules.BlockFor(tt.url))
+		})
+	}
+}
+
+func TestNewLinkRuleSet(t *testing.T) {
+	set, err := NewLinkRuleSet(config.LinkPreviewConfig{
+		Rules: []config.LinkPreviewRule{{Host: "example.com", Block: "embed"}},
+		Teams: map[string]config.LinkPreviewTeam{
+			"workspace-research": {
+				Rules:    []config.LinkPreviewRule{{Host: "youtube.com", Block: "bookmark"}},
+				Fallback: "paragraph",
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	defaults := set.ForWorkspace("workspace-other")
+	assert.Equal(t, LinkBlockEmbed, defaults.BlockFor("https://docs.example.com/a"))
+	assert.Equal(t, LinkBlockVideo, defaults.BlockFor("https://youtu.be/abc"))
+	assert.Equal(t, LinkBlockBookmark, defaults.BlockFor("https://blog.test/post"))
+
+	// Team rules come first, then the shared rules, then the team's fallback
+	team := set.ForWorkspace("workspace-research")
+	assert.Equal(t, LinkBlockBookmark, team.BlockFor("https://youtube.com/watch?v=abc"))
+	assert.Equal(t, LinkBlockEmbed, team.BlockFor("https://example.com/a"))
+	assert.Equal(t, LinkBlockParagraph, team.BlockFor("https://blog.test/post"))
+}
+
+func TestNewLinkRuleSet_Invalid(t *testing.T) {
+	_, err := NewLinkRuleSet(config.LinkPreviewConfig{
+		Rules: []config.LinkPreviewRule{{Host: "example.com", Block: "iframe"}},
+	})
+	assert.EqualError(t, err, `unknown link preview block "iframe"`)
+
+	_, err = NewLinkRuleSet(config.LinkPreviewConfig{
+		Teams: map[string]config.LinkPreviewTeam{"ws": {Rules: []config.LinkPreviewRule{{Block: "embed"}}}},
+	})
+	assert.EqualError(t, err, `link previews for team ws: link preview rule for "embed" needs a host or an extension`)
+}
+
+func TestMapParagraph_StandaloneLinks(t *testing.T) {
+	blocks := mapMarkdown(t, "https://example.com/research\n\n<https://youtu.be/abc>\n\n[https://figma.com/file/x](https://figma.com/file/x)\n\nRead https://example.com/research today.\n")
+	require.Len(t, blocks, 4)
+
+	bookmark, ok := blocks[0].Block.(*notionapi.BookmarkBlock)
+	require.True(t, ok, "expected bookmark block, got %T", blocks[0].Block)
+	assert.Equal(t, "https://example.com/research", bookmark.Bookmark.URL)
+
+	video, ok := blocks[1].Block.(*notionapi.VideoBlock)
+	require.True(t, ok, "expected video block, got %T", blocks[1].Block)
+	assert.Equal(t, "https://youtu.be/abc", video.Video.External.URL)
+
+	embed, ok := blocks[2].Block.(*notionapi.EmbedBlock)
+	require.True(t, ok, "expected embed block, got %T", blocks[2].Block)
+	assert.Equal(t, "https://figma.com/file/x", embed.Embed.URL)
+
+	// Links inside a sentence stay part of the text
+	_, ok = blocks[3].Block.(*notionapi.ParagraphBlock)
+	assert.True(t, ok, "expected paragraph block, got %T", blocks[3].Block)
+}
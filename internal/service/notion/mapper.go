@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"github.com/jomei/notionapi"
 	"github.com/obi2na/petrel/internal/logger"
+	petrelmodels "github.com/obi2na/petrel/internal/models"
 	utils "github.com/obi2na/petrel/internal/pkg"
 	"github.com/yuin/goldmark/ast"
+	extast "github.com/yuin/goldmark/extension/ast"
 	"go.uber.org/zap"
 	"reflect"
 	"strings"
@@ -15,6 +17,9 @@ import (
 type BlockWithChildren struct {
 	Block    notionapi.Block
 	Children []*BlockWithChildren
+	// ListStart is the number an ordered list starts counting from, set on
+	// its first item when it is not 1.
+	ListStart int `json:",omitempty"`
 }
 
 func (b *BlockWithChildren) AddChild(ctx context.Context, child *BlockWithChildren) {
@@ -26,20 +31,41 @@ func (b *BlockWithChildren) AddChild(ctx context.Context, child *BlockWithChildr
 	b.Children = append(b.Children, child)
 }
 
-type mappingContext struct {
-	currentParent *BlockWithChildren               // current parent container (nil if non-parent container)
-	stack         *utils.Stack[*BlockWithChildren] // stack to track nested parent container
-	result        []*BlockWithChildren             // Final list of top-level blocks
+// MappingContext is the state of one mapping run. Mappers add their blocks
+// to it, open containers for the blocks nested inside them, and report
+// content that could not be carried over.
+type MappingContext struct {
+	currentParent *BlockWithChildren              // current parent container (nil if non-parent container)
+	stack         *utils.Stack[parentBlock]       // stack to track nested parent container
+	open          map[ast.Node]bool               // AST nodes with a parent on the stack
+	consumed      map[ast.Node]bool               // blocks already mapped as part of their container
+	result        []*BlockWithChildren            // Final list of top-level blocks
+	report        petrelmodels.MappingReport      // content that could not be carried over to Notion
+	openToggles   int                             // <details> toggles still waiting for their </details>
+	skipChildren  bool                            // set by a mapper that already consumed the node's children
+	resolver      ReferenceResolver               // finds wiki-link and mention targets (optional)
+	linkRules     *LinkRules                      // blocks for standalone links
+	nodes         map[*BlockWithChildren]ast.Node // the AST node each container was opened for
 }
 
-func newMappingContext() *mappingContext {
-	return &mappingContext{
-		stack:  utils.NewStack[*BlockWithChildren](),
-		result: []*BlockWithChildren{},
+func newMappingContext(opts MapOptions) *MappingContext {
+	c := &MappingContext{
+		stack:     utils.NewStack[parentBlock](),
+		open:      make(map[ast.Node]bool),
+		consumed:  make(map[ast.Node]bool),
+		nodes:     make(map[*BlockWithChildren]ast.Node),
+		result:    []*BlockWithChildren{},
+		resolver:  opts.Resolver,
+		linkRules: opts.LinkRules,
 	}
+	if c.linkRules == nil {
+		c.linkRules = &DefaultLinkRules
+	}
+	return c
 }
 
-func (c *mappingContext) addBlock(ctx context.Context, b *BlockWithChildren) {
+// AddBlock adds b under the innermost open container, or at the top level.
+func (c *MappingContext) AddBlock(ctx context.Context, b *BlockWithChildren) {
 	if b == nil {
 		logger.With(ctx).Warn("Attempted to add nil block to context")
 		return
@@ -51,32 +77,166 @@ func (c *mappingContext) addBlock(ctx context.Context, b *BlockWithChildren) {
 	}
 }
 
-func (c *mappingContext) pushParent(ctx context.Context, b *BlockWithChildren) {
+// Drop records node as content that has no place in the Notion page.
+func (c *MappingContext) Drop(ctx context.Context, node ast.Node, source []byte, reason string) {
+	d := petrelmodels.DroppedNode{
+		Kind:   node.Kind().String(),
+		Line:   utils.NodeLine(node, source),
+		Reason: reason,
+	}
+	logger.With(ctx).Warn("Dropping markdown content",
+		zap.String("node", d.Kind), zap.Int("line", d.Line), zap.String("reason", d.Reason))
+	c.report.Dropped = append(c.report.Dropped, d)
+}
+
+func (c *MappingContext) fail(ctx context.Context, node ast.Node, source []byte, err error) {
+	e := petrelmodels.MappingError{
+		Kind:    node.Kind().String(),
+		Line:    utils.NodeLine(node, source),
+		Message: err.Error(),
+	}
+	logger.With(ctx).Error("Error mapping node",
+		zap.String("node", e.Kind), zap.Int("line", e.Line), zap.Error(err))
+	c.report.Errors = append(c.report.Errors, e)
+}
+
+// RichText builds the rich text for n and records anything stripped on the way.
+func (c *MappingContext) RichText(ctx context.Context, n ast.Node, source []byte) []notionapi.RichText {
+	b := buildRichText(ctx, n, source, c.resolver)
+	for _, d := range b.dropped {
+		c.Drop(ctx, d.node, source, d.reason)
+	}
+	for _, u := range b.unresolved {
+		c.Warn(ctx, u.node, source, u.message)
+	}
+	return b.result()
+}
+
+// MappingLintRule is the rule ID of the warnings Warn records.
+const MappingLintRule = "notion-mapping"
+
+// Warn records content that was carried over in a degraded form.
+func (c *MappingContext) Warn(ctx context.Context, node ast.Node, source []byte, message string) {
+	line, column := utils.NodePosition(node, source)
+	w := utils.LintWarning{Rule: MappingLintRule, Severity: utils.SeverityWarning, Line: line, Column: column, Message: message}
+	logger.With(ctx).Warn("Markdown content degraded for Notion",
+		zap.Int("line", w.Line), zap.String("message", w.Message))
+	c.report.Warnings = append(c.report.Warnings, w)
+}
+
+// LeadText returns the rich text of a container's first paragraph, which
+// Notion shows as the container block's own text, and marks the paragraph
+// as mapped. Any blocks after it become the container's children.
+func (c *MappingContext) LeadText(ctx context.Context, container ast.Node, source []byte) []notionapi.RichText {
+	switch first := container.FirstChild().(type) {
+	case *ast.Paragraph, *ast.TextBlock:
+		c.consumed[first] = true
+		return c.RichText(ctx, first, source)
+	}
+	return []notionapi.RichText{}
+}
+
+// parentBlock is a container on the parent stack together with the AST node
+// that opened it. HTML toggles have no node; their closing tag pops them.
+type parentBlock struct {
+	node  ast.Node
+	block *BlockWithChildren
+}
+
+// PushParent makes b the parent of the blocks that follow until the walk
+// leaves node, or until closeToggle is called when node is nil.
+func (c *MappingContext) PushParent(ctx context.Context, node ast.Node, b *BlockWithChildren) {
 	if b == nil {
 		logger.With(ctx).Warn("Attempted to push nil parent to stack")
 		return
 	}
 	logger.With(ctx).Debug("Pushing parent to stack")
-	c.stack.Push(b)
+	c.stack.Push(parentBlock{node: node, block: b})
+	if node != nil {
+		c.open[node] = true
+		c.nodes[b] = node
+	}
 	c.currentParent = b
 }
 
-func (c *mappingContext) popParent() {
-	c.stack.Pop()
+// popUntil pops parents up to and including the first one for which done
+// returns true.
+func (c *MappingContext) popUntil(done func(parentBlock) bool) {
+	for {
+		top, ok := c.stack.Pop()
+		if !ok {
+			break
+		}
+		if top.node == nil {
+			c.openToggles--
+		} else {
+			delete(c.open, top.node)
+		}
+		if done(top) {
+			break
+		}
+	}
 	if top, ok := c.stack.Peek(); ok {
-		c.currentParent = top
+		c.currentParent = top.block
 	} else {
 		c.currentParent = nil
 	}
 }
 
+// closeParent pops the parent opened by node, along with any HTML toggle
+// left open inside it.
+func (c *MappingContext) closeParent(node ast.Node) {
+	if c.open[node] {
+		c.popUntil(func(p parentBlock) bool { return p.node == node })
+	}
+}
+
+// SkipChildren stops the walk from descending into the node being mapped,
+// for mappers that handle its children themselves.
+func (c *MappingContext) SkipChildren() {
+	c.skipChildren = true
+}
+
+// closeToggle pops the innermost open HTML toggle. Markdown containers
+// opened inside it end with it.
+func (c *MappingContext) closeToggle() {
+	c.popUntil(func(p parentBlock) bool { return p.node == nil })
+}
+
+// MarkdownToNotionMapper maps a Markdown AST to Notion blocks and reports
+// any content that could not be carried over.
 type MarkdownToNotionMapper interface {
-	Map(ctx context.Context, doc ast.Node, source []byte) ([]*BlockWithChildren, error)
+	Map(ctx context.Context, doc ast.Node, source []byte, opts MapOptions) ([]*BlockWithChildren, petrelmodels.MappingReport, error)
+	// Capabilities describes what Map carries over, for linting drafts
+	// before they are staged.
+	Capabilities() utils.PlatformCapabilities
+	// SetChildren attaches children to a block Map produced, for staging,
+	// and reports whether the block can hold any.
+	SetChildren(block notionapi.Block, children []notionapi.Block) bool
+}
+
+// MapOptions tunes a single mapping run for its destination.
+type MapOptions struct {
+	// Resolver turns `[[Page Title]]` and `@person` into Notion mentions.
+	// Without one they are kept as plain text.
+	Resolver ReferenceResolver
+	// LinkRules picks the block for links that stand alone in a paragraph.
+	// DefaultLinkRules apply when nil.
+	LinkRules *LinkRules
 }
 
-type MapperFunc func(ctx context.Context, node ast.Node, source []byte, mc *mappingContext) error
+// MapperFunc maps one block node of the Markdown AST. Inline content is
+// read through mc.RichText; children are mapped by the walk afterwards
+// unless the mapper calls mc.SkipChildren.
+type MapperFunc func(ctx context.Context, node ast.Node, source []byte, mc *MappingContext) error
+
+// ChildrenSetter attaches children to blocks of the types it knows and
+// reports whether block was one of them.
+type ChildrenSetter func(block notionapi.Block, children []notionapi.Block) bool
+
 type PetrelMarkdownToNotionMapper struct {
-	mapperMap map[reflect.Type]MapperFunc
+	mapperMap    map[reflect.Type]MapperFunc
+	childSetters []ChildrenSetter // for blocks from extensions
 }
 
 func NewPetrelMarkdownToNotionMapper() *PetrelMarkdownToNotionMapper {
@@ -85,56 +245,144 @@ func NewPetrelMarkdownToNotionMapper() *PetrelMarkdownToNotionMapper {
 	}
 }
 
+// RegisterMappers registers the mappers for CommonMark, GFM and Petrel's
+// own Markdown extensions.
 func (p *PetrelMarkdownToNotionMapper) RegisterMappers() {
-	// TODO: register mappers here
-	p.mapperMap[reflect.TypeOf(&ast.Document{})] = mapDocument
-	p.mapperMap[reflect.TypeOf(&ast.Heading{})] = mapHeading
-	p.mapperMap[reflect.TypeOf(&ast.Paragraph{})] = mapParagraph
-	p.mapperMap[reflect.TypeOf(&ast.ListItem{})] = mapList
-	p.mapperMap[reflect.TypeOf(&ast.Blockquote{})] = mapQuote
-	p.mapperMap[reflect.TypeOf(&ast.FencedCodeBlock{})] = mapCodeBlock
-	p.mapperMap[reflect.TypeOf(&ast.List{})] = mapDocument
+	p.Register(&ast.Document{}, mapDocument)
+	p.Register(&ast.Heading{}, mapHeading)
+	p.Register(&ast.Paragraph{}, mapParagraph)
+	p.Register(&ast.ListItem{}, mapList)
+	p.Register(&ast.Blockquote{}, mapQuote)
+	p.Register(&ast.FencedCodeBlock{}, mapCodeBlock)
+	p.Register(&ast.CodeBlock{}, mapCodeBlock)
+	p.Register(&ast.List{}, mapDocument)
+	p.Register(&ast.TextBlock{}, mapParagraph)
+	p.Register(&extast.Table{}, mapTable)
+	p.Register(&ast.ThematicBreak{}, mapThematicBreak)
+	p.Register(&ast.HTMLBlock{}, mapHTMLBlock)
+	p.Register(&utils.MathBlock{}, mapMathBlock)
+}
+
+// Register maps AST nodes of the same type as node with fn, replacing any
+// mapper registered for it before. Blocks from custom goldmark extensions
+// are dropped from the draft until a mapper is registered for them.
+func (p *PetrelMarkdownToNotionMapper) Register(node ast.Node, fn MapperFunc) {
+	p.mapperMap[reflect.TypeOf(node)] = fn
+}
+
+// RegisterChildren lets blocks that the built-in setter does not know, such
+// as custom blocks from an extension, hold children.
+func (p *PetrelMarkdownToNotionMapper) RegisterChildren(fn ChildrenSetter) {
+	p.childSetters = append(p.childSetters, fn)
 }
 
-func (p *PetrelMarkdownToNotionMapper) Map(ctx context.Context, doc ast.Node, source []byte) ([]*BlockWithChildren, error) {
+func (p *PetrelMarkdownToNotionMapper) SetChildren(block notionapi.Block, children []notionapi.Block) bool {
+	if setChildren(block, children) {
+		return true
+	}
+	for _, fn := range p.childSetters {
+		if fn(block, children) {
+			return true
+		}
+	}
+	return false
+}
+
+// Capabilities is derived from the registered mappers, so blocks from an
+// extension are only reported as dropped until it registers a mapper.
+func (p *PetrelMarkdownToNotionMapper) Capabilities() utils.PlatformCapabilities {
+	blocks := make(map[reflect.Type]bool, len(p.mapperMap)+3)
+	for nodeType := range p.mapperMap {
+		blocks[nodeType] = true
+	}
+	if _, ok := p.mapperMap[reflect.TypeOf(&extast.Table{})]; ok {
+		// mapTable maps the rows and cells itself
+		for nodeType := range utils.BlockTypes(&extast.TableHeader{}, &extast.TableRow{}, &extast.TableCell{}) {
+			blocks[nodeType] = true
+		}
+	}
+	return utils.PlatformCapabilities{
+		Name:            "Notion",
+		Blocks:          blocks,
+		MaxHeadingLevel: 3, // see mapHeading
+		HTMLTags:        convertedHTMLTags(),
+		InlineHTMLTags:  inlineHTMLTags,
+		Footnotes:       false,
+	}
+}
+
+func (p *PetrelMarkdownToNotionMapper) Map(ctx context.Context, doc ast.Node, source []byte, opts MapOptions) ([]*BlockWithChildren, petrelmodels.MappingReport, error) {
 	logger.With(ctx).Info("Mapping markdown to Notion blocks")
-	mapCtx := newMappingContext()
+	mapCtx := newMappingContext(opts)
 
 	err := ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
 		if !entering {
-			if isParentBlock(n) {
-				mapCtx.popParent()
-			}
+			mapCtx.closeParent(n)
 			return ast.WalkContinue, nil
 		}
 
+		// Inline nodes are mapped as part of their block's rich text
+		if n.Type() != ast.TypeBlock {
+			return ast.WalkContinue, nil
+		}
+		if mapCtx.consumed[n] {
+			return ast.WalkSkipChildren, nil
+		}
+
 		fn, ok := p.mapperMap[reflect.TypeOf(n)]
 		if !ok {
-			// Don’t log inline types as unsupported
-			logger.With(ctx).Debug("Unsupported block-level node", zap.String("node", n.Kind().String()))
-			return ast.WalkContinue, nil
+			mapCtx.Drop(ctx, n, source, "no Notion mapper for this block")
+			return ast.WalkSkipChildren, nil
 		}
 
 		if err := fn(ctx, n, source, mapCtx); err != nil {
-			logger.With(ctx).Error("Error mapping node", zap.Error(err), zap.String("node", n.Kind().String()))
+			mapCtx.fail(ctx, n, source, err)
 		}
 
+		if mapCtx.skipChildren {
+			mapCtx.skipChildren = false
+			return ast.WalkSkipChildren, nil
+		}
 		return ast.WalkContinue, nil
 	})
 	if err != nil {
 		logger.With(ctx).Error("Error walking AST", zap.Error(err))
-		return nil, err
+		return nil, mapCtx.report, err
+	}
+
+	p.dropUnnestable(ctx, mapCtx, mapCtx.result, source)
+
+	if !mapCtx.report.Empty() {
+		logger.With(ctx).Warn("Some markdown content was not carried over to Notion",
+			zap.Int("dropped", len(mapCtx.report.Dropped)), zap.Int("errors", len(mapCtx.report.Errors)))
 	}
 
-	return mapCtx.result, nil
+	return mapCtx.result, mapCtx.report, nil
 }
 
-func isParentBlock(n ast.Node) bool {
-	switch n.(type) {
-	case *ast.List, *ast.ListItem, *ast.Blockquote:
-		return true
-	default:
-		return false
+// dropUnnestable reports and removes the children of blocks that cannot
+// hold any, which would otherwise be lost when the draft is staged.
+func (p *PetrelMarkdownToNotionMapper) dropUnnestable(ctx context.Context, c *MappingContext, blocks []*BlockWithChildren, source []byte) {
+	for _, b := range blocks {
+		if len(b.Children) == 0 {
+			continue
+		}
+		// Staging attaches the children, so clearing them here is harmless
+		if p.SetChildren(b.Block, nil) {
+			p.dropUnnestable(ctx, c, b.Children, source)
+			continue
+		}
+		d := petrelmodels.DroppedNode{
+			Kind:   string(b.Block.GetType()),
+			Reason: fmt.Sprintf("Notion cannot nest blocks in a %s block; %d block(s) dropped", b.Block.GetType(), countAll(b.Children)),
+		}
+		if node, ok := c.nodes[b]; ok {
+			d.Kind, d.Line = node.Kind().String(), utils.NodeLine(node, source)
+		}
+		logger.With(ctx).Warn("Dropping nested blocks",
+			zap.String("node", d.Kind), zap.Int("line", d.Line), zap.String("reason", d.Reason))
+		c.report.Dropped = append(c.report.Dropped, d)
+		b.Children = nil
 	}
 }
 
@@ -145,7 +393,7 @@ func extractText(n ast.Node, source []byte) string {
 	walk = func(node ast.Node) {
 		switch v := node.(type) {
 		case *ast.Text:
-			textBuilder.Write(v.Segment.Value(source))
+			textBuilder.WriteString(unescapeText(v.Segment.Value(source)))
 
 		case *ast.Emphasis:
 			for child := v.FirstChild(); child != nil; child = child.NextSibling() {
@@ -173,14 +421,14 @@ func extractText(n ast.Node, source []byte) string {
 	return textBuilder.String()
 }
 
-func mapHeading(ctx context.Context, node ast.Node, source []byte, ctxMap *mappingContext) error {
+func mapHeading(ctx context.Context, node ast.Node, source []byte, ctxMap *MappingContext) error {
 	heading, ok := node.(*ast.Heading)
 	if !ok {
 		err := fmt.Errorf("expected *ast.Heading but got %T", node)
 		logger.With(ctx).Error("error casting", zap.Error(err))
 		return err
 	}
-	text := extractText(heading, source)
+	richText := ctxMap.RichText(ctx, heading, source)
 
 	var block notionapi.Block
 	switch heading.Level {
@@ -191,14 +439,7 @@ func mapHeading(ctx context.Context, node ast.Node, source []byte, ctxMap *mappi
 				Object: notionapi.ObjectTypeBlock,
 			},
 			Heading1: notionapi.Heading{
-				RichText: []notionapi.RichText{
-					{
-						Type: notionapi.ObjectTypeText,
-						Text: &notionapi.Text{
-							Content: text,
-						},
-					},
-				},
+				RichText: richText,
 			},
 		}
 	case 2:
@@ -208,14 +449,7 @@ func mapHeading(ctx context.Context, node ast.Node, source []byte, ctxMap *mappi
 				Object: notionapi.ObjectTypeBlock,
 			},
 			Heading2: notionapi.Heading{
-				RichText: []notionapi.RichText{
-					{
-						Type: notionapi.ObjectTypeText,
-						Text: &notionapi.Text{
-							Content: text,
-						},
-					},
-				},
+				RichText: richText,
 			},
 		}
 	default:
@@ -225,24 +459,34 @@ func mapHeading(ctx context.Context, node ast.Node, source []byte, ctxMap *mappi
 				Object: notionapi.ObjectTypeBlock,
 			},
 			Heading3: notionapi.Heading{
-				RichText: []notionapi.RichText{
-					{
-						Type: notionapi.ObjectTypeText,
-						Text: &notionapi.Text{
-							Content: text,
-						},
-					},
-				},
+				RichText: richText,
 			},
 		}
 	}
 
-	ctxMap.addBlock(ctx, &BlockWithChildren{Block: block})
+	ctxMap.AddBlock(ctx, &BlockWithChildren{Block: block})
 	return nil
 }
 
-func mapParagraph(ctx context.Context, node ast.Node, source []byte, ctxMap *mappingContext) error {
-	text := extractText(node, source)
+func mapParagraph(ctx context.Context, node ast.Node, source []byte, ctxMap *MappingContext) error {
+	// A paragraph made only of images becomes one image block per image
+	if images, ok := standaloneImages(node, source); ok {
+		for _, img := range images {
+			caption := plainCaption(extractText(img, source))
+			ctxMap.AddBlock(ctx, newImageBlock(string(img.Destination), caption))
+		}
+		return nil
+	}
+
+	// So does a paragraph made of a single link, unless the rules keep it as text
+	if link, ok := standaloneLink(node, source); ok {
+		if block := ctxMap.linkRules.BlockFor(link); block != LinkBlockParagraph {
+			ctxMap.AddBlock(ctx, newLinkBlock(block, link))
+			return nil
+		}
+	}
+
+	richText := ctxMap.RichText(ctx, node, source)
 
 	block := &notionapi.ParagraphBlock{
 		BasicBlock: notionapi.BasicBlock{
@@ -250,29 +494,22 @@ func mapParagraph(ctx context.Context, node ast.Node, source []byte, ctxMap *map
 			Type:   notionapi.BlockTypeParagraph,
 		},
 		Paragraph: notionapi.Paragraph{
-			RichText: []notionapi.RichText{
-				{
-					Type: notionapi.ObjectTypeText,
-					Text: &notionapi.Text{
-						Content: text,
-					},
-				},
-			},
+			RichText: richText,
 		},
 	}
 
-	ctxMap.addBlock(ctx, &BlockWithChildren{Block: block})
+	ctxMap.AddBlock(ctx, &BlockWithChildren{Block: block})
 	return nil
 }
 
-func mapBulletedList(ctx context.Context, node ast.Node, source []byte, ctxMap *mappingContext) error {
+func mapBulletedList(ctx context.Context, node ast.Node, source []byte, ctxMap *MappingContext) error {
 	item, ok := node.(*ast.ListItem)
 	if !ok {
 		err := fmt.Errorf("expected *ast.ListItem but got %T", node)
 		logger.With(ctx).Error("error casting", zap.Error(err))
 		return err
 	}
-	text := extractText(item, source)
+	richText := ctxMap.LeadText(ctx, item, source)
 
 	block := &notionapi.BulletedListItemBlock{
 		BasicBlock: notionapi.BasicBlock{
@@ -280,29 +517,24 @@ func mapBulletedList(ctx context.Context, node ast.Node, source []byte, ctxMap *
 			Type:   notionapi.BlockTypeBulletedListItem,
 		},
 		BulletedListItem: notionapi.ListItem{
-			RichText: []notionapi.RichText{
-				{
-					Type: notionapi.ObjectTypeText,
-					Text: &notionapi.Text{
-						Content: text,
-					},
-				},
-			},
+			RichText: richText,
 		},
 	}
 
 	b := &BlockWithChildren{Block: block}
-	ctxMap.addBlock(ctx, b)
+	ctxMap.AddBlock(ctx, b)
 
-	// If this item contains nested content, push to stack
-	if isParentBlock(item) {
-		ctxMap.pushParent(ctx, b)
-	}
+	// Nested content becomes the item's children
+	ctxMap.PushParent(ctx, item, b)
 	return nil
 }
 
-func mapQuote(ctx context.Context, node ast.Node, source []byte, ctxMap *mappingContext) error {
-	text := extractText(node, source)
+func mapQuote(ctx context.Context, node ast.Node, source []byte, ctxMap *MappingContext) error {
+	if kind, ok := admonitionKind(node, source); ok {
+		return mapCallout(ctx, node, source, ctxMap, kind)
+	}
+
+	richText := ctxMap.LeadText(ctx, node, source)
 
 	block := &notionapi.QuoteBlock{
 		BasicBlock: notionapi.BasicBlock{
@@ -310,37 +542,50 @@ func mapQuote(ctx context.Context, node ast.Node, source []byte, ctxMap *mapping
 			Object: notionapi.ObjectTypeBlock,
 		},
 		Quote: notionapi.Quote{
-			RichText: []notionapi.RichText{
-				{
-					Type: notionapi.ObjectTypeText,
-					Text: &notionapi.Text{
-						Content: text,
-					},
-				},
-			},
+			RichText: richText,
 		},
 	}
 
-	ctxMap.addBlock(ctx, &BlockWithChildren{Block: block})
+	b := &BlockWithChildren{Block: block}
+	ctxMap.AddBlock(ctx, b)
+
+	// Further paragraphs and nested blocks become the quote's children
+	ctxMap.PushParent(ctx, node, b)
 	return nil
 }
 
-func mapCodeBlock(ctx context.Context, node ast.Node, source []byte, mapCtx *mappingContext) error {
-	codeBlock, ok := node.(*ast.FencedCodeBlock)
-	if !ok {
-		err := fmt.Errorf("error casting codeblock to FencedCodeBlock")
+// mapCodeBlock maps fenced and indented code blocks. Fence languages are
+// normalised to the names Notion accepts, and a `{title=…}` attribute on the
+// fence becomes the caption.
+func mapCodeBlock(ctx context.Context, node ast.Node, source []byte, mapCtx *MappingContext) error {
+	var language string
+	var caption []notionapi.RichText
+	switch codeBlock := node.(type) {
+	case *ast.FencedCodeBlock:
+		if codeBlock.Info != nil {
+			info, attrs := parseFenceInfo(string(codeBlock.Info.Segment.Value(source)))
+			language = info
+			caption = plainCaption(attrs["title"])
+		}
+	case *ast.CodeBlock:
+	default:
+		err := fmt.Errorf("expected a code block but got %T", node)
 		logger.With(ctx).Error("casting error", zap.Error(err))
 		return err
 	}
 
+	notionLang, ok := notionLanguage(language)
+	if !ok {
+		mapCtx.Warn(ctx, node, source, fmt.Sprintf("Code language %q is not supported by Notion; using plain text", language))
+	}
+
 	// Extract the code content
 	var contentBuilder strings.Builder
-	for i := 0; i < codeBlock.Lines().Len(); i++ {
-		line := codeBlock.Lines().At(i)
+	for i := 0; i < node.Lines().Len(); i++ {
+		line := node.Lines().At(i)
 		contentBuilder.Write(line.Value(source))
 	}
 	codeContent := contentBuilder.String()
-	language := string(codeBlock.Language(source))
 
 	// Create Notion code block
 	block := &BlockWithChildren{
@@ -351,32 +596,26 @@ func mapCodeBlock(ctx context.Context, node ast.Node, source []byte, mapCtx *map
 				Object: notionapi.ObjectTypeBlock,
 			},
 			Code: notionapi.Code{
-				RichText: []notionapi.RichText{
-					{
-						Type: "text",
-						Text: &notionapi.Text{
-							Content: codeContent,
-						},
-					},
-				},
-				Language: language,
+				RichText: plainRichText(codeContent),
+				Language: notionLang,
+				Caption:  caption,
 			},
 		},
 	}
 
 	// Add the block to the mapping context
-	mapCtx.addBlock(ctx, block)
+	mapCtx.AddBlock(ctx, block)
 	return nil
 }
 
-func mapNumberedList(ctx context.Context, node ast.Node, source []byte, ctxMap *mappingContext) error {
+func mapNumberedList(ctx context.Context, node ast.Node, source []byte, ctxMap *MappingContext) error {
 	item, ok := node.(*ast.ListItem)
 	if !ok {
 		err := fmt.Errorf("expected *ast.ListItem but got %T", node)
 		logger.With(ctx).Error("casting error", zap.Error(err))
 		return err
 	}
-	text := extractText(item, source)
+	richText := ctxMap.LeadText(ctx, item, source)
 
 	block := &notionapi.NumberedListItemBlock{
 		BasicBlock: notionapi.BasicBlock{
@@ -384,28 +623,21 @@ func mapNumberedList(ctx context.Context, node ast.Node, source []byte, ctxMap *
 			Type:   notionapi.BlockTypeNumberedListItem,
 		},
 		NumberedListItem: notionapi.ListItem{
-			RichText: []notionapi.RichText{
-				{
-					Type: notionapi.ObjectTypeText,
-					Text: &notionapi.Text{
-						Content: text,
-					},
-				},
-			},
+			RichText: richText,
 		},
 	}
 
 	b := &BlockWithChildren{Block: block}
-	ctxMap.addBlock(ctx, b)
-
-	if isParentBlock(item) {
-		ctxMap.pushParent(ctx, b)
+	if list, ok := item.Parent().(*ast.List); ok && list.Start > 1 && item.PreviousSibling() == nil {
+		b.ListStart = list.Start
 	}
+	ctxMap.AddBlock(ctx, b)
+	ctxMap.PushParent(ctx, item, b)
 
 	return nil
 }
 
-func mapList(ctx context.Context, node ast.Node, source []byte, ctxMap *mappingContext) error {
+func mapList(ctx context.Context, node ast.Node, source []byte, ctxMap *MappingContext) error {
 	item, ok := node.(*ast.ListItem)
 	if !ok {
 		err := fmt.Errorf("expected *ast.ListItem, got %T", node)
@@ -427,13 +659,249 @@ func mapList(ctx context.Context, node ast.Node, source []byte, ctxMap *mappingC
 		return err
 	}
 
+	if _, ok := taskCheckBox(item); ok {
+		return mapToDo(ctx, node, source, ctxMap)
+	}
 	if list.IsOrdered() {
 		return mapNumberedList(ctx, node, source, ctxMap)
 	}
 	return mapBulletedList(ctx, node, source, ctxMap)
 }
 
-func mapDocument(ctx context.Context, node ast.Node, source []byte, ctxMap *mappingContext) error {
+// taskCheckBox returns the GFM task checkbox that opens a list item, if any.
+func taskCheckBox(item *ast.ListItem) (*extast.TaskCheckBox, bool) {
+	first := item.FirstChild()
+	if first == nil {
+		return nil, false
+	}
+	checkBox, ok := first.FirstChild().(*extast.TaskCheckBox)
+	return checkBox, ok
+}
+
+func mapToDo(ctx context.Context, node ast.Node, source []byte, ctxMap *MappingContext) error {
+	item, ok := node.(*ast.ListItem)
+	if !ok {
+		err := fmt.Errorf("expected *ast.ListItem but got %T", node)
+		logger.With(ctx).Error("casting error", zap.Error(err))
+		return err
+	}
+	checkBox, ok := taskCheckBox(item)
+	if !ok {
+		err := fmt.Errorf("list item is not a task list item")
+		logger.With(ctx).Error("missing task checkbox", zap.Error(err))
+		return err
+	}
+	richText := trimLeadingSpace(ctxMap.LeadText(ctx, item, source))
+
+	block := &notionapi.ToDoBlock{
+		BasicBlock: notionapi.BasicBlock{
+			Object: notionapi.ObjectTypeBlock,
+			Type:   notionapi.BlockTypeToDo,
+		},
+		ToDo: notionapi.ToDo{
+			RichText: richText,
+			Checked:  checkBox.IsChecked,
+		},
+	}
+
+	b := &BlockWithChildren{Block: block}
+	ctxMap.AddBlock(ctx, b)
+	ctxMap.PushParent(ctx, item, b)
+
+	return nil
+}
+
+func mapDocument(ctx context.Context, node ast.Node, source []byte, ctxMap *MappingContext) error {
 	// No-op mapper — just ensures children are walked
 	return nil
 }
+
+func mapTable(ctx context.Context, node ast.Node, source []byte, ctxMap *MappingContext) error {
+	table, ok := node.(*extast.Table)
+	if !ok {
+		err := fmt.Errorf("expected *extast.Table but got %T", node)
+		logger.With(ctx).Error("error casting", zap.Error(err))
+		return err
+	}
+	// Rows and cells are all read here
+	ctxMap.SkipChildren()
+
+	// Collect each row's cells, noting whether the first row is a real header
+	var rows [][][]notionapi.RichText
+	hasHeader := false
+	width := len(table.Alignments)
+	for child := table.FirstChild(); child != nil; child = child.NextSibling() {
+		var cells [][]notionapi.RichText
+		for cell := child.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			cells = append(cells, ctxMap.RichText(ctx, cell, source))
+		}
+
+		if _, isHeader := child.(*extast.TableHeader); isHeader {
+			// A header row made only of empty cells carries no information
+			if isBlankRow(cells) {
+				continue
+			}
+			hasHeader = true
+		}
+
+		if len(cells) > width {
+			width = len(cells)
+		}
+		rows = append(rows, cells)
+	}
+
+	if width == 0 || len(rows) == 0 {
+		logger.With(ctx).Warn("Skipping empty table")
+		return nil
+	}
+
+	tableBlock := &BlockWithChildren{
+		Block: &notionapi.TableBlock{
+			BasicBlock: notionapi.BasicBlock{
+				Type:   notionapi.BlockTypeTableBlock,
+				Object: notionapi.ObjectTypeBlock,
+			},
+			Table: notionapi.Table{
+				TableWidth:      width,
+				HasColumnHeader: hasHeader,
+			},
+		},
+	}
+
+	// Notion requires every row to have exactly table_width cells, so ragged rows are padded
+	for _, cells := range rows {
+		for len(cells) < width {
+			cells = append(cells, []notionapi.RichText{})
+		}
+		tableBlock.AddChild(ctx, &BlockWithChildren{
+			Block: &notionapi.TableRowBlock{
+				BasicBlock: notionapi.BasicBlock{
+					Type:   notionapi.BlockTypeTableRowBlock,
+					Object: notionapi.ObjectTypeBlock,
+				},
+				TableRow: notionapi.TableRow{
+					Cells: cells,
+				},
+			},
+		})
+	}
+
+	ctxMap.AddBlock(ctx, tableBlock)
+	return nil
+}
+
+func isBlankRow(cells [][]notionapi.RichText) bool {
+	for _, cell := range cells {
+		for _, rt := range cell {
+			if rt.Text != nil && strings.TrimSpace(rt.Text.Content) != "" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// standaloneImages returns the images of a paragraph that holds nothing but
+// images with absolute URLs (and the whitespace between them).
+func standaloneImages(node ast.Node, source []byte) ([]*ast.Image, bool) {
+	var images []*ast.Image
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		switch v := child.(type) {
+		case *ast.Image:
+			if !isAbsoluteURL(string(v.Destination)) {
+				return nil, false
+			}
+			images = append(images, v)
+		case *ast.Text:
+			if strings.TrimSpace(string(v.Segment.Value(source))) != "" {
+				return nil, false
+			}
+		default:
+			return nil, false
+		}
+	}
+	return images, len(images) > 0
+}
+
+func newImageBlock(url string, caption []notionapi.RichText) *BlockWithChildren {
+	return &BlockWithChildren{
+		Block: &notionapi.ImageBlock{
+			BasicBlock: notionapi.BasicBlock{
+				Type:   notionapi.BlockTypeImage,
+				Object: notionapi.ObjectTypeBlock,
+			},
+			Image: notionapi.Image{
+				Type:     notionapi.FileTypeExternal,
+				External: &notionapi.FileObject{URL: url},
+				Caption:  caption,
+			},
+		},
+	}
+}
+
+func newDividerBlock() *BlockWithChildren {
+	return &BlockWithChildren{
+		Block: &notionapi.DividerBlock{
+			BasicBlock: notionapi.BasicBlock{
+				Type:   notionapi.BlockTypeDivider,
+				Object: notionapi.ObjectTypeBlock,
+			},
+		},
+	}
+}
+
+func mapThematicBreak(ctx context.Context, node ast.Node, source []byte, ctxMap *MappingContext) error {
+	ctxMap.AddBlock(ctx, newDividerBlock())
+	return nil
+}
+
+func mapHTMLBlock(ctx context.Context, node ast.Node, source []byte, ctxMap *MappingContext) error {
+	htmlBlock, ok := node.(*ast.HTMLBlock)
+	if !ok {
+		err := fmt.Errorf("expected *ast.HTMLBlock but got %T", node)
+		logger.With(ctx).Error("error casting", zap.Error(err))
+		return err
+	}
+
+	var raw strings.Builder
+	for i := 0; i < htmlBlock.Lines().Len(); i++ {
+		line := htmlBlock.Lines().At(i)
+		raw.Write(line.Value(source))
+	}
+	if htmlBlock.HasClosure() {
+		raw.Write(htmlBlock.ClosureLine.Value(source))
+	}
+
+	for _, reason := range convertHTMLBlock(ctx, raw.String(), ctxMap) {
+		ctxMap.Drop(ctx, htmlBlock, source, reason)
+	}
+	return nil
+}
+
+func mapMathBlock(ctx context.Context, node ast.Node, source []byte, ctxMap *MappingContext) error {
+	math, ok := node.(*utils.MathBlock)
+	if !ok {
+		err := fmt.Errorf("expected *utils.MathBlock but got %T", node)
+		logger.With(ctx).Error("error casting", zap.Error(err))
+		return err
+	}
+
+	expression := math.Expression(source)
+	if expression == "" {
+		ctxMap.Drop(ctx, math, source, "empty display math block")
+		return nil
+	}
+
+	ctxMap.AddBlock(ctx, &BlockWithChildren{
+		Block: &notionapi.EquationBlock{
+			BasicBlock: notionapi.BasicBlock{
+				Type:   notionapi.BlockTypeEquation,
+				Object: notionapi.ObjectTypeBlock,
+			},
+			Equation: notionapi.Equation{
+				Expression: expression,
+			},
+		},
+	})
+	return nil
+}
@@ -0,0 +1,408 @@
+package notion
+
+import (
+	"context"
+	"github.com/jomei/notionapi"
+	"github.com/obi2na/petrel/internal/logger"
+	utils "github.com/obi2na/petrel/internal/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func newTestParser() utils.Parser {
+	return utils.NewDefaultMarkdownParser()
+}
+
+func mapMarkdown(t *testing.T, markdown string) []*BlockWithChildren {
+	t.Helper()
+	logger.Init()
+
+	doc, source, err := newTestParser().Parse(markdown)
+	require.NoError(t, err)
+
+	mapper := NewPetrelMarkdownToNotionMapper()
+	mapper.RegisterMappers()
+
+	blocks, _, err := mapper.Map(context.Background(), doc, source, MapOptions{})
+	require.NoError(t, err)
+	return blocks
+}
+
+func TestMapParagraph_InlineFormatting(t *testing.T) {
+	blocks := mapMarkdown(t, "Plain **bold** *italic* ~~struck~~ `code` [link](https://example.com) <https://auto.dev>")
+	require.Len(t, blocks, 1)
+
+	paragraph, ok := blocks[0].Block.(*notionapi.ParagraphBlock)
+	require.True(t, ok, "expected paragraph block, got %T", blocks[0].Block)
+
+	type segment struct {
+		content string
+		bold    bool
+		italic  bool
+		strike  bool
+		code    bool
+		link    string
+	}
+	var got []segment
+	for _, rt := range paragraph.Paragraph.RichText {
+		s := segment{content: rt.Text.Content}
+		if rt.Annotations != nil {
+			s.bold = rt.Annotations.Bold
+			s.italic = rt.Annotations.Italic
+			s.strike = rt.Annotations.Strikethrough
+			s.code = rt.Annotations.Code
+		}
+		if rt.Text.Link != nil {
+			s.link = rt.Text.Link.Url
+		}
+		got = append(got, s)
+	}
+
+	expected := []segment{
+		{content: "Plain "},
+		{content: "bold", bold: true},
+		{content: " "},
+		{content: "italic", italic: true},
+		{content: " "},
+		{content: "struck", strike: true},
+		{content: " "},
+		{content: "code", code: true},
+		{content: " "},
+		{content: "link", link: "https://example.com"},
+		{content: " "},
+		{content: "https://auto.dev", link: "https://auto.dev"},
+	}
+	assert.Equal(t, expected, got)
+}
+
+func TestMapHeading_NestedEmphasis(t *testing.T) {
+	blocks := mapMarkdown(t, "# Title with ***both*** styles")
+	require.Len(t, blocks, 1)
+
+	heading, ok := blocks[0].Block.(*notionapi.Heading1Block)
+	require.True(t, ok, "expected heading_1 block, got %T", blocks[0].Block)
+	require.Len(t, heading.Heading1.RichText, 3)
+
+	both := heading.Heading1.RichText[1]
+	assert.Equal(t, "both", both.Text.Content)
+	require.NotNil(t, both.Annotations)
+	assert.True(t, both.Annotations.Bold)
+	assert.True(t, both.Annotations.Italic)
+	assert.Nil(t, heading.Heading1.RichText[0].Annotations)
+}
+
+func TestMapTable_HeaderAndRaggedRows(t *testing.T) {
+	markdown := "| Tool | Price | Notes |\n|------|-------|-------|\n| Petrel | **Free** | fast |\n| Other | $5 |\n"
+	blocks := mapMarkdown(t, markdown)
+	require.Len(t, blocks, 1)
+
+	table, ok := blocks[0].Block.(*notionapi.TableBlock)
+	require.True(t, ok, "expected table block, got %T", blocks[0].Block)
+	assert.Equal(t, 3, table.Table.TableWidth)
+	assert.True(t, table.Table.HasColumnHeader)
+	require.Len(t, blocks[0].Children, 3)
+
+	for _, child := range blocks[0].Children {
+		row, ok := child.Block.(*notionapi.TableRowBlock)
+		require.True(t, ok, "expected table row block, got %T", child.Block)
+		assert.Len(t, row.TableRow.Cells, 3)
+	}
+
+	header := blocks[0].Children[0].Block.(*notionapi.TableRowBlock)
+	assert.Equal(t, "Tool", header.TableRow.Cells[0][0].Text.Content)
+
+	body := blocks[0].Children[1].Block.(*notionapi.TableRowBlock)
+	require.NotNil(t, body.TableRow.Cells[1][0].Annotations)
+	assert.True(t, body.TableRow.Cells[1][0].Annotations.Bold)
+
+	ragged := blocks[0].Children[2].Block.(*notionapi.TableRowBlock)
+	assert.Empty(t, ragged.TableRow.Cells[2])
+}
+
+func TestMapList_TaskItemsBecomeToDos(t *testing.T) {
+	blocks := mapMarkdown(t, "- [ ] draft outline\n- [x] review **copy**\n  - [ ] nested follow-up\n- plain bullet\n")
+	require.Len(t, blocks, 3)
+
+	open, ok := blocks[0].Block.(*notionapi.ToDoBlock)
+	require.True(t, ok, "expected to_do block, got %T", blocks[0].Block)
+	assert.False(t, open.ToDo.Checked)
+	assert.Equal(t, "draft outline", open.ToDo.RichText[0].Text.Content)
+
+	done, ok := blocks[1].Block.(*notionapi.ToDoBlock)
+	require.True(t, ok, "expected to_do block, got %T", blocks[1].Block)
+	assert.True(t, done.ToDo.Checked)
+	assert.Equal(t, "review ", done.ToDo.RichText[0].Text.Content)
+
+	require.Len(t, blocks[1].Children, 1)
+	nested, ok := blocks[1].Children[0].Block.(*notionapi.ToDoBlock)
+	require.True(t, ok, "expected nested to_do block, got %T", blocks[1].Children[0].Block)
+	assert.False(t, nested.ToDo.Checked)
+
+	_, ok = blocks[2].Block.(*notionapi.BulletedListItemBlock)
+	assert.True(t, ok, "expected bulleted list item, got %T", blocks[2].Block)
+}
+
+func TestMapImagesAndDividers(t *testing.T) {
+	markdown := "![Architecture diagram](https://cdn.example.com/arch.png)\n\n---\n\nSee ![logo](./logo.png) above.\n"
+	blocks := mapMarkdown(t, markdown)
+	require.Len(t, blocks, 3)
+
+	image, ok := blocks[0].Block.(*notionapi.ImageBlock)
+	require.True(t, ok, "expected image block, got %T", blocks[0].Block)
+	assert.Equal(t, "https://cdn.example.com/arch.png", image.Image.External.URL)
+	require.Len(t, image.Image.Caption, 1)
+	assert.Equal(t, "Architecture diagram", image.Image.Caption[0].Text.Content)
+
+	_, ok = blocks[1].Block.(*notionapi.DividerBlock)
+	assert.True(t, ok, "expected divider block, got %T", blocks[1].Block)
+
+	// Relative images cannot be fetched by Notion, so only their alt text survives
+	paragraph, ok := blocks[2].Block.(*notionapi.ParagraphBlock)
+	require.True(t, ok, "expected paragraph block, got %T", blocks[2].Block)
+	require.Len(t, paragraph.Paragraph.RichText, 1)
+	assert.Equal(t, "See logo above.", paragraph.Paragraph.RichText[0].Text.Content)
+}
+
+func TestConvertHTMLBlock(t *testing.T) {
+	logger.Init()
+	raw := "<div>\n<p>Hello <b>bold</b> <span>world</span></p>\n<hr>\n<img src=\"https://example.com/a.png\" alt=\"chart\">\n<script>alert(1)</script>\n</div>\n"
+	mc := newMappingContext(MapOptions{})
+	dropped := convertHTMLBlock(context.Background(), raw, mc)
+	blocks := mc.result
+	require.Len(t, blocks, 3)
+
+	paragraph, ok := blocks[0].Block.(*notionapi.ParagraphBlock)
+	require.True(t, ok, "expected paragraph block, got %T", blocks[0].Block)
+	require.Len(t, paragraph.Paragraph.RichText, 3)
+	assert.Equal(t, "Hello ", paragraph.Paragraph.RichText[0].Text.Content)
+	assert.Equal(t, "bold", paragraph.Paragraph.RichText[1].Text.Content)
+	assert.True(t, paragraph.Paragraph.RichText[1].Annotations.Bold)
+	assert.Equal(t, " world", paragraph.Paragraph.RichText[2].Text.Content)
+
+	_, ok = blocks[1].Block.(*notionapi.DividerBlock)
+	assert.True(t, ok, "expected divider block, got %T", blocks[1].Block)
+
+	image, ok := blocks[2].Block.(*notionapi.ImageBlock)
+	require.True(t, ok, "expected image block, got %T", blocks[2].Block)
+	assert.Equal(t, "https://example.com/a.png", image.Image.External.URL)
+
+	assert.Equal(t, []string{"<span> stripped", "<script> content dropped"}, dropped)
+}
+
+func TestMapInlineHTML(t *testing.T) {
+	blocks := mapMarkdown(t, "Press <kbd>Ctrl</kbd> then <u>underline</u><br>next")
+	require.Len(t, blocks, 1)
+
+	paragraph, ok := blocks[0].Block.(*notionapi.ParagraphBlock)
+	require.True(t, ok, "expected paragraph block, got %T", blocks[0].Block)
+
+	var text string
+	for _, rt := range paragraph.Paragraph.RichText {
+		text += rt.Text.Content
+	}
+	assert.Equal(t, "Press Ctrl then underline\nnext", text)
+	assert.True(t, paragraph.Paragraph.RichText[1].Annotations.Underline)
+}
+
+func TestMapQuote_AdmonitionBecomesCallout(t *testing.T) {
+	blocks := mapMarkdown(t, "> [!WARNING]\n> Do **not** share credentials.\n\n> plain quote\n")
+	require.Len(t, blocks, 2)
+
+	callout, ok := blocks[0].Block.(*notionapi.CalloutBlock)
+	require.True(t, ok, "expected callout block, got %T", blocks[0].Block)
+	assert.Equal(t, notionapi.ColorYellowBackground.String(), callout.Callout.Color)
+	require.NotNil(t, callout.Callout.Icon)
+	assert.Equal(t, "⚠️", string(*callout.Callout.Icon.Emoji))
+	assert.Equal(t, "Do ", callout.Callout.RichText[0].Text.Content)
+	assert.Empty(t, blocks[0].Children)
+
+	quote, ok := blocks[1].Block.(*notionapi.QuoteBlock)
+	require.True(t, ok, "expected quote block, got %T", blocks[1].Block)
+	assert.Equal(t, "plain quote", plainText(quote.Quote.RichText))
+	assert.Empty(t, blocks[1].Children)
+}
+
+func TestMapQuote_ParagraphsBecomeChildren(t *testing.T) {
+	blocks := mapMarkdown(t, "> First paragraph\n> continues.\n>\n> Second paragraph.\n>\n> - point\n")
+	require.Len(t, blocks, 1)
+
+	quote, ok := blocks[0].Block.(*notionapi.QuoteBlock)
+	require.True(t, ok, "expected quote block, got %T", blocks[0].Block)
+	assert.Equal(t, "First paragraph continues.", plainText(quote.Quote.RichText))
+
+	require.Len(t, blocks[0].Children, 2)
+	paragraph, ok := blocks[0].Children[0].Block.(*notionapi.ParagraphBlock)
+	require.True(t, ok, "expected paragraph block, got %T", blocks[0].Children[0].Block)
+	assert.Equal(t, "Second paragraph.", plainText(paragraph.Paragraph.RichText))
+	_, ok = blocks[0].Children[1].Block.(*notionapi.BulletedListItemBlock)
+	assert.True(t, ok, "expected bulleted list item, got %T", blocks[0].Children[1].Block)
+}
+
+func TestMapList_NestedContent(t *testing.T) {
+	markdown := "- parent\n\n  More about the parent.\n\n  ```go\n  x()\n  ```\n\n  3. third\n  4. fourth\n     - deep\n- sibling\n"
+	blocks := mapMarkdown(t, markdown)
+	require.Len(t, blocks, 2)
+
+	parent, ok := blocks[0].Block.(*notionapi.BulletedListItemBlock)
+	require.True(t, ok, "expected bulleted list item, got %T", blocks[0].Block)
+	assert.Equal(t, "parent", plainText(parent.BulletedListItem.RichText))
+
+	children := blocks[0].Children
+	require.Len(t, children, 4)
+	assert.IsType(t, &notionapi.ParagraphBlock{}, children[0].Block)
+	assert.IsType(t, &notionapi.CodeBlock{}, children[1].Block)
+
+	third, ok := children[2].Block.(*notionapi.NumberedListItemBlock)
+	require.True(t, ok, "expected numbered list item, got %T", children[2].Block)
+	assert.Equal(t, "third", plainText(third.NumberedListItem.RichText))
+	assert.Equal(t, 3, children[2].ListStart)
+	assert.Zero(t, children[3].ListStart)
+
+	require.Len(t, children[3].Children, 1)
+	deep, ok := children[3].Children[0].Block.(*notionapi.BulletedListItemBlock)
+	require.True(t, ok, "expected bulleted list item, got %T", children[3].Children[0].Block)
+	assert.Equal(t, "deep", plainText(deep.BulletedListItem.RichText))
+
+	sibling, ok := blocks[1].Block.(*notionapi.BulletedListItemBlock)
+	require.True(t, ok, "expected bulleted list item, got %T", blocks[1].Block)
+	assert.Equal(t, "sibling", plainText(sibling.BulletedListItem.RichText))
+	assert.Empty(t, blocks[1].Children)
+}
+
+func TestMapHTMLBlock_DetailsBecomesToggle(t *testing.T) {
+	markdown := "<details>\n<summary>Audit <b>notes</b></summary>\n\nHidden paragraph.\n\n- hidden item\n\n</details>\n\nVisible paragraph.\n"
+	blocks := mapMarkdown(t, markdown)
+	require.Len(t, blocks, 2)
+
+	toggle, ok := blocks[0].Block.(*notionapi.ToggleBlock)
+	require.True(t, ok, "expected toggle block, got %T", blocks[0].Block)
+	require.Len(t, toggle.Toggle.RichText, 2)
+	assert.Equal(t, "Audit ", toggle.Toggle.RichText[0].Text.Content)
+	assert.True(t, toggle.Toggle.RichText[1].Annotations.Bold)
+
+	require.Len(t, blocks[0].Children, 2)
+	_, ok = blocks[0].Children[0].Block.(*notionapi.ParagraphBlock)
+	assert.True(t, ok, "expected paragraph child, got %T", blocks[0].Children[0].Block)
+	_, ok = blocks[0].Children[1].Block.(*notionapi.BulletedListItemBlock)
+	assert.True(t, ok, "expected bulleted list child, got %T", blocks[0].Children[1].Block)
+
+	_, ok = blocks[1].Block.(*notionapi.ParagraphBlock)
+	assert.True(t, ok, "expected paragraph block, got %T", blocks[1].Block)
+}
+
+func TestMapHTMLBlock_UnclosedSummary(t *testing.T) {
+	logger.Init()
+	markdown := "<details>\n<summary>Audit notes\n\nHidden paragraph.\n\n</details>\n"
+	doc, source, err := newTestParser().Parse(markdown)
+	require.NoError(t, err)
+
+	mapper := NewPetrelMarkdownToNotionMapper()
+	mapper.RegisterMappers()
+	blocks, report, err := mapper.Map(context.Background(), doc, source, MapOptions{})
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+
+	toggle, ok := blocks[0].Block.(*notionapi.ToggleBlock)
+	require.True(t, ok, "expected toggle block, got %T", blocks[0].Block)
+	assert.Equal(t, "Audit notes", plainText(toggle.Toggle.RichText))
+	require.Len(t, blocks[0].Children, 1)
+
+	require.NotNil(t, report)
+	var reasons []string
+	for _, d := range report.Dropped {
+		reasons = append(reasons, d.Reason)
+	}
+	assert.Contains(t, reasons, "unclosed <summary>")
+}
+
+func TestMapMath(t *testing.T) {
+	blocks := mapMarkdown(t, "Mass-energy $E = mc^2$ holds.\n\n$$\n\\sum_{i=1}^n i\n$$\n")
+	require.Len(t, blocks, 2)
+
+	paragraph, ok := blocks[0].Block.(*notionapi.ParagraphBlock)
+	require.True(t, ok, "expected paragraph block, got %T", blocks[0].Block)
+	require.Len(t, paragraph.Paragraph.RichText, 3)
+	equation := paragraph.Paragraph.RichText[1]
+	assert.Equal(t, richTextTypeEquation, equation.Type)
+	require.NotNil(t, equation.Equation)
+	assert.Equal(t, "E = mc^2", equation.Equation.Expression)
+
+	block, ok := blocks[1].Block.(*notionapi.EquationBlock)
+	require.True(t, ok, "expected equation block, got %T", blocks[1].Block)
+	assert.Equal(t, "\\sum_{i=1}^n i", block.Equation.Expression)
+}
+
+func TestMapCodeBlock_Languages(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		language string
+		caption  string
+	}{
+		{name: "alias", markdown: "```js\nx()\n```\n", language: "javascript"},
+		{name: "notion name", markdown: "```Python\nx()\n```\n", language: "python"},
+		{name: "no language", markdown: "```\nx()\n```\n", language: "plain text"},
+		{name: "unknown language", markdown: "```brainfuck\n+++\n```\n", language: "plain text"},
+		{name: "title attribute", markdown: "```go {title=\"main.go\" linenos}\nx()\n```\n", language: "go", caption: "main.go"},
+		{name: "title without language", markdown: "```{title=setup.sh}\nx\n```\n", language: "plain text", caption: "setup.sh"},
+		{name: "indented", markdown: "    x()\n", language: "plain text"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := mapMarkdown(t, tt.markdown)
+			require.Len(t, blocks, 1)
+
+			block, ok := blocks[0].Block.(*notionapi.CodeBlock)
+			require.True(t, ok, "expected code block, got %T", blocks[0].Block)
+			assert.Equal(t, tt.language, block.Code.Language)
+			assert.Equal(t, tt.caption, plainText(block.Code.Caption))
+		})
+	}
+}
+
+func TestMapCodeBlock_UnknownLanguageWarns(t *testing.T) {
+	logger.Init()
+	doc, source, err := newTestParser().Parse("Intro\n\n```brainfuck\n+++\n```\n")
+	require.NoError(t, err)
+
+	mapper := NewPetrelMarkdownToNotionMapper()
+	mapper.RegisterMappers()
+	_, report, err := mapper.Map(context.Background(), doc, source, MapOptions{})
+	require.NoError(t, err)
+
+	assert.True(t, report.Empty())
+	assert.Equal(t, []utils.LintWarning{
+		{Rule: MappingLintRule, Severity: utils.SeverityWarning, Line: 3, Column: 4, Message: `Code language "brainfuck" is not supported by Notion; using plain text`},
+	}, report.Warnings)
+}
+
+func TestCapabilities_LintsWhatMapDegrades(t *testing.T) {
+	mapper := NewPetrelMarkdownToNotionMapper()
+	mapper.RegisterMappers()
+	capabilities := mapper.Capabilities()
+	assert.Equal(t, "Notion", capabilities.Name)
+	assert.Equal(t, 3, capabilities.MaxHeadingLevel)
+
+	markdown := "# Plan\n\n| Owner | Task |\n| --- | --- |\n| Ana | <b>Ship</b> |\n\n" +
+		"<details>\n<summary>More</summary>\n\n##### Notes\n\nSee the <kbd>docs</kbd>.[^1]\n\n</details>\n\n<section>Legal</section>\n"
+	doc, source, err := newTestParser().Parse(markdown)
+	require.NoError(t, err)
+	rules := utils.LintRuleSet{"heading-depth": utils.RuleOff, "heading-skipped-level": utils.RuleOff, "spelling": utils.RuleOff}
+	warnings, err := utils.NewPetrelMarkdownLinter().Lint(doc, source, utils.LintOptions{Rules: rules, Platform: &capabilities})
+	require.NoError(t, err)
+
+	var messages []string
+	for _, w := range warnings {
+		if w.Rule == "platform-compatibility" {
+			messages = append(messages, w.Message)
+		}
+	}
+	assert.Equal(t, []string{
+		"Notion has no h5; this heading becomes h3",
+		"Notion strips <kbd> tags",
+		"Notion has no footnotes; [^1] stays as plain text",
+		"Notion strips <section> tags",
+	}, messages)
+}
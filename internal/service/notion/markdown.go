@@ -0,0 +1,814 @@
+package notion
+
+import (
+	"context"
+	"fmt"
+	"github.com/jomei/notionapi"
+	"github.com/obi2na/petrel/internal/logger"
+	"go.uber.org/zap"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NotionToMarkdownMapper is the reverse of MarkdownToNotionMapper: it reads
+// Notion blocks back into Markdown so edits made in a Notion draft can be
+// pulled back into Petrel.
+//
+// Mapping the output of PetrelMarkdownToNotionMapper back to Markdown and
+// forward again yields the same blocks.
+type NotionToMarkdownMapper interface {
+	Map(ctx context.Context, blocks []*BlockWithChildren) (string, error)
+}
+
+// BlockMapperFunc renders one Notion block, including its children, as Markdown.
+type BlockMapperFunc func(ctx context.Context, node *BlockWithChildren, mc *markdownContext) (string, error)
+
+type PetrelNotionToMarkdownMapper struct {
+	mapperMap map[reflect.Type]BlockMapperFunc
+}
+
+func NewPetrelNotionToMarkdownMapper() *PetrelNotionToMarkdownMapper {
+	return &PetrelNotionToMarkdownMapper{
+		mapperMap: make(map[reflect.Type]BlockMapperFunc),
+	}
+}
+
+func (p *PetrelNotionToMarkdownMapper) RegisterMappers() {
+	p.mapperMap[reflect.TypeOf(&notionapi.ParagraphBlock{})] = renderParagraph
+	p.mapperMap[reflect.TypeOf(&notionapi.Heading1Block{})] = renderHeading
+	p.mapperMap[reflect.TypeOf(&notionapi.Heading2Block{})] = renderHeading
+	p.mapperMap[reflect.TypeOf(&notionapi.Heading3Block{})] = renderHeading
+	p.mapperMap[reflect.TypeOf(&notionapi.BulletedListItemBlock{})] = renderListItem
+	p.mapperMap[reflect.TypeOf(&notionapi.NumberedListItemBlock{})] = renderListItem
+	p.mapperMap[reflect.TypeOf(&notionapi.ToDoBlock{})] = renderListItem
+	p.mapperMap[reflect.TypeOf(&notionapi.QuoteBlock{})] = renderQuote
+	p.mapperMap[reflect.TypeOf(&notionapi.CalloutBlock{})] = renderCallout
+	p.mapperMap[reflect.TypeOf(&notionapi.ToggleBlock{})] = renderToggle
+	p.mapperMap[reflect.TypeOf(&notionapi.CodeBlock{})] = renderCodeBlock
+	p.mapperMap[reflect.TypeOf(&notionapi.EquationBlock{})] = renderEquation
+	p.mapperMap[reflect.TypeOf(&notionapi.DividerBlock{})] = renderDivider
+	p.mapperMap[reflect.TypeOf(&notionapi.ImageBlock{})] = renderImage
+	p.mapperMap[reflect.TypeOf(&notionapi.BookmarkBlock{})] = renderLinkBlock
+	p.mapperMap[reflect.TypeOf(&notionapi.EmbedBlock{})] = renderLinkBlock
+	p.mapperMap[reflect.TypeOf(&notionapi.VideoBlock{})] = renderLinkBlock
+	p.mapperMap[reflect.TypeOf(&notionapi.TableBlock{})] = renderTable
+}
+
+func (p *PetrelNotionToMarkdownMapper) Map(ctx context.Context, blocks []*BlockWithChildren) (string, error) {
+	logger.With(ctx).Info("Mapping Notion blocks to markdown")
+	mc := &markdownContext{mapper: p}
+
+	markdown, err := mc.renderBlocks(ctx, blocks)
+	if err != nil {
+		logger.With(ctx).Error("Error mapping Notion blocks", zap.Error(err))
+		return "", err
+	}
+	if markdown == "" {
+		return "", nil
+	}
+	return markdown + "\n", nil
+}
+
+type markdownContext struct {
+	mapper     *PetrelNotionToMarkdownMapper
+	listNumber int // position of the numbered list item being rendered
+}
+
+// renderBlocks renders sibling blocks, separating them with blank lines
+// except between consecutive items of the same kind of list, which form one
+// tight list.
+func (c *markdownContext) renderBlocks(ctx context.Context, nodes []*BlockWithChildren) (string, error) {
+	var sb strings.Builder
+	var prev notionapi.Block
+	number := 0
+
+	for _, node := range nodes {
+		if node == nil || node.Block == nil {
+			continue
+		}
+		fn, ok := c.mapper.mapperMap[reflect.TypeOf(node.Block)]
+		if !ok {
+			logger.With(ctx).Warn("Unsupported Notion block", zap.String("type", node.Block.GetType().String()))
+			continue
+		}
+
+		if _, ok := node.Block.(*notionapi.NumberedListItemBlock); ok {
+			number++
+			if number == 1 && node.ListStart > 0 {
+				number = node.ListStart
+			}
+		} else {
+			number = 0
+		}
+		c.listNumber = number
+
+		markdown, err := fn(ctx, node, c)
+		if err != nil {
+			logger.With(ctx).Error("Error mapping Notion block", zap.Error(err), zap.String("type", node.Block.GetType().String()))
+			continue
+		}
+		if markdown == "" {
+			continue
+		}
+
+		if sb.Len() > 0 {
+			if listKind(prev) != "" && listKind(prev) == listKind(node.Block) {
+				sb.WriteString("\n")
+			} else {
+				sb.WriteString("\n\n")
+			}
+		}
+		sb.WriteString(markdown)
+		prev = node.Block
+	}
+	return sb.String(), nil
+}
+
+// renderChildren renders the children of node with every line indented by
+// prefix, ready to append to the parent's own text.
+func (c *markdownContext) renderChildren(ctx context.Context, node *BlockWithChildren, prefix string) (string, error) {
+	children := blockChildren(node)
+	markdown, err := c.renderBlocks(ctx, children)
+	if err != nil || markdown == "" {
+		return "", err
+	}
+
+	// A sublist may follow its item directly; anything else needs a blank
+	// line so it is not read as a continuation of the item's text
+	sep := "\n" + strings.TrimRight(prefix, " ") + "\n"
+	if listKind(children[0].Block) != "" {
+		sep = "\n"
+	}
+	return sep + prefixLines(markdown, prefix), nil
+}
+
+// listKind returns the Markdown list a block renders into: "-" for bullets
+// and to-dos, "." for numbered items and "" for anything else.
+func listKind(block notionapi.Block) string {
+	switch block.(type) {
+	case *notionapi.BulletedListItemBlock, *notionapi.ToDoBlock:
+		return "-"
+	case *notionapi.NumberedListItemBlock:
+		return "."
+	default:
+		return ""
+	}
+}
+
+// blockChildren returns the children of node, falling back to those nested
+// inside the block itself, as in blocks read back from the Notion API.
+func blockChildren(node *BlockWithChildren) []*BlockWithChildren {
+	if len(node.Children) > 0 {
+		return node.Children
+	}
+
+	var nested notionapi.Blocks
+	switch b := node.Block.(type) {
+	case *notionapi.ParagraphBlock:
+		nested = b.Paragraph.Children
+	case *notionapi.ToggleBlock:
+		nested = b.Toggle.Children
+	case *notionapi.BulletedListItemBlock:
+		nested = b.BulletedListItem.Children
+	case *notionapi.NumberedListItemBlock:
+		nested = b.NumberedListItem.Children
+	case *notionapi.QuoteBlock:
+		nested = b.Quote.Children
+	case *notionapi.TableBlock:
+		nested = b.Table.Children
+	case *notionapi.ToDoBlock:
+		nested = b.ToDo.Children
+	case *notionapi.CalloutBlock:
+		nested = b.Callout.Children
+	}
+
+	children := make([]*BlockWithChildren, 0, len(nested))
+	for _, child := range nested {
+		children = append(children, &BlockWithChildren{Block: child})
+	}
+	return children
+}
+
+// prefixLines prefixes every line of s, leaving blank lines without
+// trailing whitespace.
+func prefixLines(s, prefix string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = strings.TrimRight(prefix, " ")
+		} else {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func renderParagraph(ctx context.Context, node *BlockWithChildren, mc *markdownContext) (string, error) {
+	block, ok := node.Block.(*notionapi.ParagraphBlock)
+	if !ok {
+		return "", fmt.Errorf("expected *notionapi.ParagraphBlock but got %T", node.Block)
+	}
+	return renderRichText(block.Paragraph.RichText, hardLineBreak, false), nil
+}
+
+func renderHeading(ctx context.Context, node *BlockWithChildren, mc *markdownContext) (string, error) {
+	var marker string
+	var richText []notionapi.RichText
+	switch b := node.Block.(type) {
+	case *notionapi.Heading1Block:
+		marker, richText = "#", b.Heading1.RichText
+	case *notionapi.Heading2Block:
+		marker, richText = "##", b.Heading2.RichText
+	case *notionapi.Heading3Block:
+		marker, richText = "###", b.Heading3.RichText
+	default:
+		return "", fmt.Errorf("expected a heading block but got %T", node.Block)
+	}
+
+	text := renderRichText(richText, htmlLineBreak, false)
+	if text == "" {
+		return marker, nil
+	}
+	return marker + " " + text, nil
+}
+
+func renderListItem(ctx context.Context, node *BlockWithChildren, mc *markdownContext) (string, error) {
+	var marker string
+	var richText []notionapi.RichText
+	indent := "  "
+	switch b := node.Block.(type) {
+	case *notionapi.BulletedListItemBlock:
+		marker, richText = "-", b.BulletedListItem.RichText
+	case *notionapi.NumberedListItemBlock:
+		marker, richText = fmt.Sprintf("%d.", mc.listNumber), b.NumberedListItem.RichText
+		indent = strings.Repeat(" ", len(marker)+1)
+	case *notionapi.ToDoBlock:
+		marker, richText = "- [ ]", b.ToDo.RichText
+		if b.ToDo.Checked {
+			marker = "- [x]"
+		}
+	default:
+		return "", fmt.Errorf("expected a list item block but got %T", node.Block)
+	}
+
+	item := marker
+	if text := renderRichText(richText, hardLineBreak, false); text != "" {
+		item += " " + text
+	}
+
+	// Children are indented to line up with the item's text
+	children, err := mc.renderChildren(ctx, node, indent)
+	if err != nil {
+		return "", err
+	}
+	return item + children, nil
+}
+
+func renderQuote(ctx context.Context, node *BlockWithChildren, mc *markdownContext) (string, error) {
+	block, ok := node.Block.(*notionapi.QuoteBlock)
+	if !ok {
+		return "", fmt.Errorf("expected *notionapi.QuoteBlock but got %T", node.Block)
+	}
+	return mc.renderQuoted(ctx, node, renderRichText(block.Quote.RichText, hardLineBreak, false))
+}
+
+func renderCallout(ctx context.Context, node *BlockWithChildren, mc *markdownContext) (string, error) {
+	block, ok := node.Block.(*notionapi.CalloutBlock)
+	if !ok {
+		return "", fmt.Errorf("expected *notionapi.CalloutBlock but got %T", node.Block)
+	}
+
+	text := renderRichText(block.Callout.RichText, hardLineBreak, false)
+	kind, emoji := calloutKind(block.Callout)
+	switch {
+	case kind != "":
+		// Start the text on its own line so the marker stays recognisable
+		text = "[!" + kind + "]\n" + text
+	case emoji != "":
+		// Callouts with a custom icon degrade to a quote led by the icon
+		text = emoji + " " + text
+	}
+	return mc.renderQuoted(ctx, node, strings.TrimRight(text, "\n"))
+}
+
+// calloutKind finds the admonition a callout was created from by its icon,
+// returning the icon itself when it matches none of them.
+func calloutKind(callout notionapi.Callout) (string, string) {
+	if callout.Icon == nil || callout.Icon.Emoji == nil {
+		return "", ""
+	}
+	emoji := string(*callout.Icon.Emoji)
+	for kind, style := range admonitions {
+		if style.emoji == emoji {
+			return kind, emoji
+		}
+	}
+	return "", emoji
+}
+
+func (c *markdownContext) renderQuoted(ctx context.Context, node *BlockWithChildren, text string) (string, error) {
+	children, err := c.renderChildren(ctx, node, "")
+	if err != nil {
+		return "", err
+	}
+	if text == "" && children == "" {
+		return ">", nil
+	}
+	return prefixLines(strings.TrimLeft(text+children, "\n"), "> "), nil
+}
+
+func renderToggle(ctx context.Context, node *BlockWithChildren, mc *markdownContext) (string, error) {
+	block, ok := node.Block.(*notionapi.ToggleBlock)
+	if !ok {
+		return "", fmt.Errorf("expected *notionapi.ToggleBlock but got %T", node.Block)
+	}
+
+	summary := renderHTMLRichText(block.Toggle.RichText)
+	children, err := mc.renderBlocks(ctx, blockChildren(node))
+	if err != nil {
+		return "", err
+	}
+
+	// Markdown inside <details> must be set off from the HTML by blank lines
+	var sb strings.Builder
+	sb.WriteString("<details>\n<summary>" + summary + "</summary>\n")
+	if children != "" {
+		sb.WriteString("\n" + children + "\n\n")
+	}
+	sb.WriteString("</details>")
+	return sb.String(), nil
+}
+
+func renderCodeBlock(ctx context.Context, node *BlockWithChildren, mc *markdownContext) (string, error) {
+	block, ok := node.Block.(*notionapi.CodeBlock)
+	if !ok {
+		return "", fmt.Errorf("expected *notionapi.CodeBlock but got %T", node.Block)
+	}
+
+	code := plainText(block.Code.RichText)
+	if !strings.HasSuffix(code, "\n") {
+		code += "\n"
+	}
+	info := fenceLanguage(block.Code.Language)
+	if title := plainText(block.Code.Caption); title != "" {
+		quote := `"`
+		if strings.Contains(title, quote) {
+			quote = "'"
+		}
+		info = strings.TrimSpace(info + " {title=" + quote + title + quote + "}")
+	}
+
+	// The fence must be longer than any backtick run inside the code, and
+	// backtick fences cannot have backticks in their info string
+	fence := strings.Repeat("`", max(3, longestRun(code, '`')+1))
+	if strings.Contains(info, "`") {
+		fence = strings.Repeat("~", max(3, longestRun(code, '~')+1))
+	}
+	return fence + info + "\n" + code + fence, nil
+}
+
+func renderEquation(ctx context.Context, node *BlockWithChildren, mc *markdownContext) (string, error) {
+	block, ok := node.Block.(*notionapi.EquationBlock)
+	if !ok {
+		return "", fmt.Errorf("expected *notionapi.EquationBlock but got %T", node.Block)
+	}
+	return "$$\n" + strings.TrimSpace(block.Equation.Expression) + "\n$$", nil
+}
+
+func renderDivider(ctx context.Context, node *BlockWithChildren, mc *markdownContext) (string, error) {
+	return "---", nil
+}
+
+func renderImage(ctx context.Context, node *BlockWithChildren, mc *markdownContext) (string, error) {
+	block, ok := node.Block.(*notionapi.ImageBlock)
+	if !ok {
+		return "", fmt.Errorf("expected *notionapi.ImageBlock but got %T", node.Block)
+	}
+	url := block.Image.GetURL()
+	if url == "" {
+		return "", fmt.Errorf("image block has no URL")
+	}
+	caption := escapeMarkdownText(plainText(block.Image.Caption), false, false, " ")
+	return "![" + caption + "](" + linkDestination(url) + ")", nil
+}
+
+// renderLinkBlock renders bookmarks, embeds and videos as a bare autolink,
+// which the Markdown mapper turns back into a link block.
+func renderLinkBlock(ctx context.Context, node *BlockWithChildren, mc *markdownContext) (string, error) {
+	var url string
+	switch block := node.Block.(type) {
+	case *notionapi.BookmarkBlock:
+		url = block.Bookmark.URL
+	case *notionapi.EmbedBlock:
+		url = block.Embed.URL
+	case *notionapi.VideoBlock:
+		if block.Video.External != nil {
+			url = block.Video.External.URL
+		} else if block.Video.File != nil {
+			url = block.Video.File.URL
+		}
+	default:
+		return "", fmt.Errorf("expected a bookmark, embed or video block but got %T", node.Block)
+	}
+	if url == "" {
+		return "", fmt.Errorf("%s block has no URL", node.Block.GetType())
+	}
+	if strings.ContainsAny(url, " <>") {
+		// Not allowed in an autolink, so spell the link out
+		return "[" + escapeMarkdownText(url, false, false, " ") + "](" + linkDestination(url) + ")", nil
+	}
+	return "<" + url + ">", nil
+}
+
+func renderTable(ctx context.Context, node *BlockWithChildren, mc *markdownContext) (string, error) {
+	block, ok := node.Block.(*notionapi.TableBlock)
+	if !ok {
+		return "", fmt.Errorf("expected *notionapi.TableBlock but got %T", node.Block)
+	}
+
+	var rows [][]string
+	width := block.Table.TableWidth
+	for _, child := range blockChildren(node) {
+		row, ok := child.Block.(*notionapi.TableRowBlock)
+		if !ok {
+			continue
+		}
+		var cells []string
+		for _, cell := range row.TableRow.Cells {
+			cells = append(cells, renderRichText(cell, htmlLineBreak, true))
+		}
+		width = max(width, len(cells))
+		rows = append(rows, cells)
+	}
+	if width == 0 {
+		return "", nil
+	}
+
+	// GFM tables always have a header row, so a table without one gets a
+	// blank header that maps back to no header
+	header := make([]string, width)
+	if block.Table.HasColumnHeader && len(rows) > 0 {
+		header, rows = rows[0], rows[1:]
+	}
+
+	var sb strings.Builder
+	writeTableRow(&sb, header, width)
+	sb.WriteString("\n|" + strings.Repeat(" --- |", width))
+	for _, row := range rows {
+		sb.WriteString("\n")
+		writeTableRow(&sb, row, width)
+	}
+	return sb.String(), nil
+}
+
+func writeTableRow(sb *strings.Builder, cells []string, width int) {
+	sb.WriteString("|")
+	for i := 0; i < width; i++ {
+		cell := ""
+		if i < len(cells) {
+			cell = cells[i]
+		}
+		sb.WriteString(" " + cell + " |")
+	}
+}
+
+// ----- Rich text -----
+
+const (
+	hardLineBreak = "\\\n"
+	htmlLineBreak = "<br>"
+)
+
+// markdownMark is an inline formatting mark that wraps a run of segments.
+type markdownMark struct {
+	kind string // "link", "bold", "italic", "strikethrough" or "underline"
+	url  string
+}
+
+func (m markdownMark) open() string {
+	switch m.kind {
+	case "link":
+		return "["
+	case "bold":
+		return "**"
+	case "italic":
+		return "*"
+	case "strikethrough":
+		return "~~"
+	default:
+		return "<u>"
+	}
+}
+
+func (m markdownMark) close() string {
+	switch m.kind {
+	case "link":
+		return "](" + linkDestination(m.url) + ")"
+	case "bold":
+		return "**"
+	case "italic":
+		return "*"
+	case "strikethrough":
+		return "~~"
+	default:
+		return "</u>"
+	}
+}
+
+// segmentMarks lists the marks of a segment, outermost first.
+func segmentMarks(rt notionapi.RichText) []markdownMark {
+	var marks []markdownMark
+	if rt.Text != nil && rt.Text.Link != nil && rt.Text.Link.Url != "" {
+		marks = append(marks, markdownMark{kind: "link", url: rt.Text.Link.Url})
+	}
+	if a := rt.Annotations; a != nil {
+		if a.Bold {
+			marks = append(marks, markdownMark{kind: "bold"})
+		}
+		if a.Italic {
+			marks = append(marks, markdownMark{kind: "italic"})
+		}
+		if a.Strikethrough {
+			marks = append(marks, markdownMark{kind: "strikethrough"})
+		}
+		if a.Underline {
+			marks = append(marks, markdownMark{kind: "underline"})
+		}
+	}
+	return marks
+}
+
+// inlineWriter renders rich-text segments as Markdown, keeping marks open
+// across segments that share them and moving whitespace outside of marks so
+// the delimiters stay valid.
+type inlineWriter struct {
+	sb        strings.Builder
+	open      []markdownMark
+	pending   string // whitespace held back until the next marks are settled
+	lineBreak string
+	table     bool
+}
+
+// renderRichText renders segments as inline Markdown. lineBreak is written
+// for every newline in the text; table escapes pipes for use in table cells.
+func renderRichText(segments []notionapi.RichText, lineBreak string, table bool) string {
+	w := &inlineWriter{lineBreak: lineBreak, table: table}
+	for _, rt := range segments {
+		w.segment(rt)
+	}
+	// Whitespace left at the very end would be dropped by Markdown anyway
+	w.settle(nil)
+	return w.sb.String()
+}
+
+func (w *inlineWriter) segment(rt notionapi.RichText) {
+	marks := segmentMarks(rt)
+	text := rt.PlainText
+	if rt.Text != nil {
+		text = rt.Text.Content
+	}
+
+	var lead, core, trail string
+	escape := false
+	switch {
+	case rt.Type == richTextTypeEquation && rt.Equation != nil:
+		core = "$" + strings.TrimSpace(rt.Equation.Expression) + "$"
+	case isPageMention(rt):
+		core = "[[" + strings.TrimSpace(text) + "]]"
+	case isUserMention(rt):
+		core = "@" + strings.Join(strings.Fields(strings.TrimPrefix(text, "@")), ".")
+	case rt.Annotations != nil && rt.Annotations.Code:
+		core = codeSpan(text, w.table)
+	case isAutoLink(text, marks):
+		core = "<" + text + ">"
+		marks = marks[1:]
+	default:
+		lead, core, trail = splitSpace(text)
+		escape = true
+	}
+
+	if core == "" {
+		w.pending += lead
+		return
+	}
+	w.settle(marks)
+	w.writeSpace(lead)
+	w.openMarks(marks)
+	if escape {
+		// Escaped against what precedes it, which is only known now
+		core = w.escape(core)
+	}
+	w.sb.WriteString(core)
+	w.pending = trail
+}
+
+// settle closes every open mark that the next segment does not share, and
+// everything opened inside it.
+func (w *inlineWriter) settle(marks []markdownMark) {
+	keep := 0
+	for keep < len(w.open) && containsMark(marks, w.open[keep]) {
+		keep++
+	}
+	for i := len(w.open) - 1; i >= keep; i-- {
+		w.sb.WriteString(w.open[i].close())
+	}
+	w.open = w.open[:keep]
+}
+
+func (w *inlineWriter) openMarks(marks []markdownMark) {
+	for _, m := range marks {
+		if !containsMark(w.open, m) {
+			w.sb.WriteString(m.open())
+			w.open = append(w.open, m)
+		}
+	}
+}
+
+func (w *inlineWriter) writeSpace(lead string) {
+	space := w.pending + lead
+	w.pending = ""
+	if w.sb.Len() == 0 {
+		// Leading whitespace would be dropped, or turn the text into code
+		return
+	}
+	w.sb.WriteString(strings.ReplaceAll(space, "\n", w.lineBreak))
+}
+
+func (w *inlineWriter) escape(s string) string {
+	out := w.sb.String()
+	atLineStart := strings.TrimSpace(out[strings.LastIndex(out, "\n")+1:]) == ""
+	return escapeMarkdownText(s, atLineStart, w.table, w.lineBreak)
+}
+
+func containsMark(marks []markdownMark, m markdownMark) bool {
+	for _, candidate := range marks {
+		if candidate == m {
+			return true
+		}
+	}
+	return false
+}
+
+// isAutoLink reports whether a segment is a bare link to its own text, which
+// renders as a Markdown autolink.
+func isAutoLink(text string, marks []markdownMark) bool {
+	if len(marks) != 1 || marks[0].kind != "link" {
+		return false
+	}
+	url := marks[0].url
+	return (url == text && isAbsoluteURL(url) && !strings.ContainsAny(url, " <>")) ||
+		(url == "mailto:"+text && !strings.ContainsAny(text, " <>"))
+}
+
+func splitSpace(s string) (string, string, string) {
+	core := strings.TrimLeftFunc(s, unicode.IsSpace)
+	lead := s[:len(s)-len(core)]
+	trimmed := strings.TrimRightFunc(core, unicode.IsSpace)
+	return lead, trimmed, core[len(trimmed):]
+}
+
+var (
+	orderedMarkerRe = regexp.MustCompile(`^\d{1,9}[.)]`)
+	entityRefRe     = regexp.MustCompile(`^&(#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[A-Za-z][A-Za-z0-9]{1,31});`)
+)
+
+// escapeMarkdownText backslash-escapes everything in s that Markdown would
+// otherwise read as syntax. atLineStart marks s as the start of a line,
+// where block markers such as `#` or `1.` also need escaping.
+func escapeMarkdownText(s string, atLineStart, table bool, lineBreak string) string {
+	var sb strings.Builder
+	escapeAt := -1
+	prev := rune(-1)
+
+	for i, r := range s {
+		if r == '\n' {
+			sb.WriteString(lineBreak)
+			atLineStart = strings.HasSuffix(lineBreak, "\n")
+			prev = r
+			continue
+		}
+		if atLineStart && r != ' ' && r != '\t' {
+			atLineStart = false
+			switch {
+			case strings.ContainsRune("#>+-=", r):
+				sb.WriteByte('\\')
+			case orderedMarkerRe.MatchString(s[i:]):
+				escapeAt = i + len(orderedMarkerRe.FindString(s[i:])) - 1
+			}
+		}
+
+		switch {
+		case i == escapeAt:
+			sb.WriteByte('\\')
+		case strings.ContainsRune("\\`*[]<~$", r):
+			sb.WriteByte('\\')
+		case r == '_':
+			// Underscores inside words never start emphasis
+			next, _ := utf8.DecodeRuneInString(s[i+1:])
+			if !isWordRune(prev) || !isWordRune(next) {
+				sb.WriteByte('\\')
+			}
+		case r == '|' && table:
+			sb.WriteByte('\\')
+		case r == '&' && entityRefRe.MatchString(s[i:]):
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+		prev = r
+	}
+	return sb.String()
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// codeSpan wraps s in enough backticks that it reads back unchanged.
+func codeSpan(s string, table bool) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	if table {
+		s = strings.ReplaceAll(s, "|", "\\|")
+	}
+	fence := strings.Repeat("`", longestRun(s, '`')+1)
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") ||
+		(strings.HasPrefix(s, " ") && strings.HasSuffix(s, " ") && strings.TrimSpace(s) != "") {
+		s = " " + s + " "
+	}
+	return fence + s + fence
+}
+
+func longestRun(s string, c byte) int {
+	longest, run := 0, 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == c {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return longest
+}
+
+func linkDestination(url string) string {
+	if strings.ContainsAny(url, " ()<>") {
+		return "<" + strings.NewReplacer("<", "\\<", ">", "\\>").Replace(url) + ">"
+	}
+	return url
+}
+
+// isPageMention and isUserMention match the mentions that the Markdown
+// mapper creates for wiki-links and @mentions. Other mentions, such as
+// dates, fall back to their plain text.
+func isPageMention(rt notionapi.RichText) bool {
+	return rt.Mention != nil && rt.Mention.Type == notionapi.MentionTypePage && strings.TrimSpace(rt.PlainText) != ""
+}
+
+func isUserMention(rt notionapi.RichText) bool {
+	return rt.Mention != nil && rt.Mention.Type == notionapi.MentionTypeUser && strings.TrimPrefix(strings.TrimSpace(rt.PlainText), "@") != ""
+}
+
+// plainText concatenates the text of segments without any formatting.
+func plainText(segments []notionapi.RichText) string {
+	var sb strings.Builder
+	for _, rt := range segments {
+		switch {
+		case rt.Text != nil:
+			sb.WriteString(rt.Text.Content)
+		case rt.Equation != nil:
+			sb.WriteString(rt.Equation.Expression)
+		default:
+			sb.WriteString(rt.PlainText)
+		}
+	}
+	return sb.String()
+}
+
+var htmlTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+// renderHTMLRichText renders segments as inline HTML, for places such as a
+// <summary> where Markdown is not parsed.
+func renderHTMLRichText(segments []notionapi.RichText) string {
+	var sb strings.Builder
+	for _, rt := range segments {
+		text := htmlTextEscaper.Replace(strings.ReplaceAll(plainText([]notionapi.RichText{rt}), "\n", " "))
+		if a := rt.Annotations; a != nil {
+			for _, tag := range []struct {
+				on   bool
+				name string
+			}{{a.Code, "code"}, {a.Underline, "u"}, {a.Strikethrough, "s"}, {a.Italic, "i"}, {a.Bold, "b"}} {
+				if tag.on {
+					text = "<" + tag.name + ">" + text + "</" + tag.name + ">"
+				}
+			}
+		}
+		if rt.Text != nil && rt.Text.Link != nil && rt.Text.Link.Url != "" {
+			text = `<a href="` + htmlTextEscaper.Replace(rt.Text.Link.Url) + `">` + text + "</a>"
+		}
+		sb.WriteString(text)
+	}
+	return sb.String()
+}
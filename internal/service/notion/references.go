@@ -0,0 +1,192 @@
+package notion
+
+import (
+	"context"
+	"github.com/jomei/notionapi"
+	"github.com/obi2na/petrel/internal/logger"
+	utils "github.com/obi2na/petrel/internal/pkg"
+	"go.uber.org/zap"
+	"strings"
+	"unicode"
+)
+
+// ReferenceResolver finds the Notion pages and people that `[[Page Title]]`
+// wiki-links and `@person` mentions refer to in a destination workspace.
+type ReferenceResolver interface {
+	ResolvePage(ctx context.Context, title string) (notionapi.ObjectID, bool, error)
+	ResolveUser(ctx context.Context, handle string) (notionapi.UserID, bool, error)
+}
+
+// NotionReferenceResolver resolves references through the Notion API for
+// one workspace integration. Lookups are cached, so it should live no
+// longer than a single staging run.
+type NotionReferenceResolver struct {
+	client      utils.NotionApiClient
+	token       string
+	pages       map[string]notionapi.ObjectID
+	users       []notionapi.User
+	usersLoaded bool
+	usersErr    error // why loading users failed; not retried
+}
+
+func NewNotionReferenceResolver(client utils.NotionApiClient, token string) *NotionReferenceResolver {
+	return &NotionReferenceResolver{
+		client: client,
+		token:  token,
+		pages:  make(map[string]notionapi.ObjectID),
+	}
+}
+
+// ResolvePage searches the workspace for a page whose title matches exactly,
+// ignoring case. Near matches are not accepted.
+func (r *NotionReferenceResolver) ResolvePage(ctx context.Context, title string) (notionapi.ObjectID, bool, error) {
+	key := strings.ToLower(strings.TrimSpace(title))
+	if id, ok := r.pages[key]; ok {
+		return id, id != "", nil
+	}
+
+	resp, err := r.client.Search(ctx, r.token, &notionapi.SearchRequest{
+		Query:    title,
+		Filter:   notionapi.SearchFilter{Property: "object", Value: "page"},
+		PageSize: 20,
+	})
+	if err != nil {
+		return "", false, err
+	}
+
+	var found notionapi.ObjectID
+	for _, result := range resp.Results {
+		if page, ok := result.(*notionapi.Page); ok && strings.EqualFold(pageTitle(page), key) {
+			found = page.ID
+			break
+		}
+	}
+	r.pages[key] = found
+	return found, found != "", nil
+}
+
+// ResolveUser matches a handle against the names and email addresses of
+// the workspace's people, ignoring case and punctuation, so `@jane.doe`
+// finds "Jane Doe". Ambiguous handles are left unresolved.
+func (r *NotionReferenceResolver) ResolveUser(ctx context.Context, handle string) (notionapi.UserID, bool, error) {
+	if err := r.loadUsers(ctx); err != nil {
+		return "", false, err
+	}
+
+	want := normalizeHandle(handle)
+	var found notionapi.UserID
+	for _, user := range r.users {
+		if user.Type != notionapi.UserTypePerson {
+			continue
+		}
+		if normalizeHandle(user.Name) != want && (user.Person == nil || normalizeHandle(emailLocalPart(user.Person.Email)) != want) {
+			continue
+		}
+		if found != "" {
+			logger.With(ctx).Warn("Ambiguous mention", zap.String("handle", handle))
+			return "", false, nil
+		}
+		found = user.ID
+	}
+	return found, found != "", nil
+}
+
+func (r *NotionReferenceResolver) loadUsers(ctx context.Context) error {
+	if r.usersLoaded {
+		return r.usersErr
+	}
+	// A failure is cached too, or every mention would list users again
+	r.usersLoaded = true
+	pagination := &notionapi.Pagination{PageSize: 100}
+	for {
+		resp, err := r.client.ListUsers(ctx, r.token, pagination)
+		if err != nil {
+			r.users, r.usersErr = nil, err
+			return err
+		}
+		r.users = append(r.users, resp.Results...)
+		if !resp.HasMore {
+			break
+		}
+		pagination.StartCursor = resp.NextCursor
+	}
+	return nil
+}
+
+func pageTitle(page *notionapi.Page) string {
+	for _, property := range page.Properties {
+		switch p := property.(type) {
+		case *notionapi.TitleProperty:
+			return plainText(p.Title)
+		case notionapi.TitleProperty:
+			return plainText(p.Title)
+		}
+	}
+	return ""
+}
+
+func normalizeHandle(s string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+func emailLocalPart(email string) string {
+	local, _, _ := strings.Cut(email, "@")
+	return local
+}
+
+// resolvePage and resolveUser treat lookup failures like a missing target,
+// so a flaky API call degrades a reference to plain text instead of failing
+// the draft.
+func (b *richTextBuilder) resolvePage(title string) (notionapi.ObjectID, bool) {
+	if b.resolver == nil {
+		return "", false
+	}
+	id, ok, err := b.resolver.ResolvePage(b.ctx, title)
+	if err != nil {
+		logger.With(b.ctx).Warn("Failed to resolve page link", zap.String("title", title), zap.Error(err))
+		return "", false
+	}
+	return id, ok
+}
+
+func (b *richTextBuilder) resolveUser(handle string) (notionapi.UserID, bool) {
+	if b.resolver == nil {
+		return "", false
+	}
+	id, ok, err := b.resolver.ResolveUser(b.ctx, handle)
+	if err != nil {
+		logger.With(b.ctx).Warn("Failed to resolve mention", zap.String("handle", handle), zap.Error(err))
+		return "", false
+	}
+	return id, ok
+}
+
+func newPageMention(id notionapi.ObjectID, title string, style inlineStyle) notionapi.RichText {
+	return notionapi.RichText{
+		Type: richTextTypeMention,
+		Mention: &notionapi.Mention{
+			Type: notionapi.MentionTypePage,
+			Page: &notionapi.PageMention{ID: id},
+		},
+		PlainText:   title,
+		Annotations: style.annotations(),
+	}
+}
+
+func newUserMention(id notionapi.UserID, handle string, style inlineStyle) notionapi.RichText {
+	return notionapi.RichText{
+		Type: richTextTypeMention,
+		Mention: &notionapi.Mention{
+			Type: notionapi.MentionTypeUser,
+			User: &notionapi.User{Object: notionapi.ObjectTypeUser, ID: id},
+		},
+		PlainText:   "@" + handle,
+		Annotations: style.annotations(),
+	}
+}
@@ -0,0 +1,116 @@
+package notion
+
+import (
+	"context"
+	"errors"
+	"github.com/jomei/notionapi"
+	"github.com/obi2na/petrel/internal/logger"
+	utils "github.com/obi2na/petrel/internal/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func newTestResolver() (*NotionReferenceResolver, *fakeNotionClient) {
+	client := &fakeNotionClient{
+		pages: map[string]notionapi.ObjectID{
+			"Launch Plan":         "page-launch",
+			"Launch Plan Archive": "page-archive",
+		},
+		users: []notionapi.User{
+			{ID: "user-jane", Type: notionapi.UserTypePerson, Name: "Jane Doe", Person: &notionapi.Person{Email: "jane@example.com"}},
+			{ID: "user-sam", Type: notionapi.UserTypePerson, Name: "Sam Lee", Person: &notionapi.Person{Email: "slee@example.com"}},
+			{ID: "bot-1", Type: notionapi.UserTypeBot, Name: "Jane"},
+		},
+	}
+	return NewNotionReferenceResolver(client, "token"), client
+}
+
+func TestNotionReferenceResolver(t *testing.T) {
+	logger.Init()
+	ctx := context.Background()
+	resolver, client := newTestResolver()
+
+	id, ok, err := resolver.ResolvePage(ctx, "launch plan")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, notionapi.ObjectID("page-launch"), id)
+
+	// Only exact titles count, and lookups are cached
+	_, ok, err = resolver.ResolvePage(ctx, "Launch")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	_, _, _ = resolver.ResolvePage(ctx, "Launch Plan")
+	assert.Equal(t, 2, client.searches)
+
+	userID, ok, err := resolver.ResolveUser(ctx, "jane.doe")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, notionapi.UserID("user-jane"), userID)
+
+	userID, ok, err = resolver.ResolveUser(ctx, "slee")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, notionapi.UserID("user-sam"), userID)
+
+	_, ok, err = resolver.ResolveUser(ctx, "nobody")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestNotionReferenceResolver_CachesUserListFailure(t *testing.T) {
+	logger.Init()
+	ctx := context.Background()
+	resolver, client := newTestResolver()
+	client.usersErr = errors.New("service unavailable")
+
+	for _, handle := range []string{"jane.doe", "slee", "jane.doe"} {
+		_, ok, err := resolver.ResolveUser(ctx, handle)
+		assert.ErrorIs(t, err, client.usersErr)
+		assert.False(t, ok)
+	}
+	assert.Equal(t, 1, client.userLists)
+}
+
+func TestMapReferences(t *testing.T) {
+	logger.Init()
+	resolver, _ := newTestResolver()
+
+	doc, source, err := newTestParser().Parse("See [[Launch Plan]] with **@jane.doe**.\n\nAlso [[Missing Page]] and @ghost.\n")
+	require.NoError(t, err)
+
+	mapper := NewPetrelMarkdownToNotionMapper()
+	mapper.RegisterMappers()
+	blocks, report, err := mapper.Map(context.Background(), doc, source, MapOptions{Resolver: resolver})
+	require.NoError(t, err)
+	require.Len(t, blocks, 2)
+	assert.True(t, report.Empty())
+
+	first := blocks[0].Block.(*notionapi.ParagraphBlock).Paragraph.RichText
+	require.Len(t, first, 5)
+	assert.Equal(t, richTextTypeMention, first[1].Type)
+	assert.Equal(t, notionapi.MentionTypePage, first[1].Mention.Type)
+	assert.Equal(t, notionapi.ObjectID("page-launch"), first[1].Mention.Page.ID)
+	assert.Equal(t, notionapi.MentionTypeUser, first[3].Mention.Type)
+	assert.Equal(t, notionapi.UserID("user-jane"), first[3].Mention.User.ID)
+	assert.True(t, first[3].Annotations.Bold)
+
+	second := blocks[1].Block.(*notionapi.ParagraphBlock).Paragraph.RichText
+	require.Len(t, second, 1)
+	assert.Equal(t, "Also [[Missing Page]] and @ghost.", second[0].Text.Content)
+	assert.Equal(t, []utils.LintWarning{
+		{Rule: MappingLintRule, Severity: utils.SeverityWarning, Line: 3, Column: 8, Message: "Unresolved page link [[Missing Page]]; kept as plain text"},
+		{Rule: MappingLintRule, Severity: utils.SeverityWarning, Line: 3, Column: 28, Message: "Unresolved mention @ghost; kept as plain text"},
+	}, report.Warnings)
+
+	// Mentions render back to the syntax they came from
+	assert.Equal(t, "See [[Launch Plan]] with **@jane.doe**.\n\nAlso \\[\\[Missing Page\\]\\] and @ghost.\n", renderMarkdown(t, blocks))
+}
+
+func TestMapReferences_NoResolver(t *testing.T) {
+	blocks := mapMarkdown(t, "See [[Launch Plan]], @jane.\n")
+
+	rt := blocks[0].Block.(*notionapi.ParagraphBlock).Paragraph.RichText
+	require.Len(t, rt, 1)
+	assert.Equal(t, "See [[Launch Plan]], @jane.", rt[0].Text.Content)
+}
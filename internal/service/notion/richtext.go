@@ -0,0 +1,409 @@
+package notion
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/jomei/notionapi"
+	utils "github.com/obi2na/petrel/internal/pkg"
+	"github.com/yuin/goldmark/ast"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/util"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// inlineStyle tracks the formatting that applies to a run of inline text
+// while walking goldmark's inline nodes.
+type inlineStyle struct {
+	bold          bool
+	italic        bool
+	strikethrough bool
+	underline     bool
+	code          bool
+	link          string
+}
+
+func (s inlineStyle) annotations() *notionapi.Annotations {
+	if !s.bold && !s.italic && !s.strikethrough && !s.underline && !s.code {
+		return nil
+	}
+	return &notionapi.Annotations{
+		Bold:          s.bold,
+		Italic:        s.italic,
+		Strikethrough: s.strikethrough,
+		Underline:     s.underline,
+		Code:          s.code,
+		Color:         notionapi.ColorDefault,
+	}
+}
+
+// richTextTypeEquation is the rich-text type for inline equations, which
+// notionapi has no constant for.
+const richTextTypeEquation notionapi.ObjectType = "equation"
+
+// richTextTypeMention is the rich-text type for page and user mentions.
+const richTextTypeMention notionapi.ObjectType = "mention"
+
+// richTextBuilder accumulates rich-text segments, merging adjacent runs
+// that share the same style so Notion gets as few segments as possible.
+type richTextBuilder struct {
+	ctx        context.Context
+	resolver   ReferenceResolver // nil leaves every reference as plain text
+	segments   []notionapi.RichText
+	styles     []inlineStyle
+	dropped    []droppedInline
+	unresolved []unresolvedReference
+}
+
+// droppedInline is inline content that could not be carried over to Notion.
+type droppedInline struct {
+	node   ast.Node
+	reason string
+}
+
+func (b *richTextBuilder) drop(node ast.Node, reason string) {
+	b.dropped = append(b.dropped, droppedInline{node: node, reason: reason})
+}
+
+// unresolvedReference is a wiki-link or mention that was kept as plain text
+// because its target could not be found.
+type unresolvedReference struct {
+	node    ast.Node
+	message string
+}
+
+func (b *richTextBuilder) unresolve(node ast.Node, message string) {
+	b.unresolved = append(b.unresolved, unresolvedReference{node: node, message: message})
+}
+
+func (b *richTextBuilder) write(content string, style inlineStyle) {
+	if content == "" {
+		return
+	}
+	if n := len(b.segments); n > 0 && b.segments[n-1].Text != nil && b.styles[n-1] == style {
+		b.segments[n-1].Text.Content += content
+		return
+	}
+	b.segments = append(b.segments, newTextRichText(content, style))
+	b.styles = append(b.styles, style)
+}
+
+func (b *richTextBuilder) writeEquation(expression string, style inlineStyle) {
+	if expression == "" {
+		return
+	}
+	b.segments = append(b.segments, notionapi.RichText{
+		Type:        richTextTypeEquation,
+		Equation:    &notionapi.Equation{Expression: expression},
+		Annotations: style.annotations(),
+	})
+	b.styles = append(b.styles, style)
+}
+
+func (b *richTextBuilder) result() []notionapi.RichText {
+	if len(b.segments) == 0 {
+		return []notionapi.RichText{}
+	}
+	return splitLongRichText(b.segments)
+}
+
+func newTextRichText(content string, style inlineStyle) notionapi.RichText {
+	rt := notionapi.RichText{
+		Type: notionapi.ObjectTypeText,
+		Text: &notionapi.Text{
+			Content: content,
+		},
+		Annotations: style.annotations(),
+	}
+	if style.link != "" {
+		rt.Text.Link = &notionapi.Link{Url: style.link}
+	}
+	return rt
+}
+
+// plainRichText wraps unformatted content in rich-text segments.
+func plainRichText(content string) []notionapi.RichText {
+	return splitLongRichText([]notionapi.RichText{newTextRichText(content, inlineStyle{})})
+}
+
+// maxRichTextLength is Notion's limit on the content of one rich-text item,
+// counted in UTF-16 code units.
+const maxRichTextLength = 2000
+
+// splitLongRichText splits text segments that exceed maxRichTextLength into
+// consecutive segments with the same formatting and link.
+func splitLongRichText(segments []notionapi.RichText) []notionapi.RichText {
+	var out []notionapi.RichText
+	for _, segment := range segments {
+		if segment.Text == nil {
+			out = append(out, segment)
+			continue
+		}
+		for _, chunk := range chunkText(segment.Text.Content, maxRichTextLength) {
+			part := segment
+			part.Text = &notionapi.Text{Content: chunk, Link: segment.Text.Link}
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// chunkText splits s into pieces of at most limit UTF-16 code units without
+// breaking a character apart.
+func chunkText(s string, limit int) []string {
+	var chunks []string
+	start, size := 0, 0
+	for i, r := range s {
+		n := utf16.RuneLen(r)
+		if n < 0 {
+			n = 1
+		}
+		if size+n > limit {
+			chunks = append(chunks, s[start:i])
+			start, size = i, 0
+		}
+		size += n
+	}
+	return append(chunks, s[start:])
+}
+
+// buildRichText converts the inline children of n into Notion rich-text
+// segments, keeping bold, italic, strikethrough, inline code and links.
+// Wiki-links and mentions become Notion mentions when resolver can find
+// their target. Anything that had to be stripped on the way is recorded on
+// the builder.
+func buildRichText(ctx context.Context, n ast.Node, source []byte, resolver ReferenceResolver) *richTextBuilder {
+	b := &richTextBuilder{ctx: ctx, resolver: resolver}
+	walkInline(n, source, inlineStyle{}, b)
+	return b
+}
+
+func walkInline(node ast.Node, source []byte, parentStyle inlineStyle, b *richTextBuilder) {
+	// Inline HTML tags open and close across sibling nodes, so the style is
+	// tracked per sibling run rather than per node.
+	style := parentStyle
+	var htmlStyles []inlineStyle
+
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		switch v := child.(type) {
+		case *ast.Text:
+			b.write(unescapeText(v.Segment.Value(source)), style)
+			if v.HardLineBreak() {
+				b.write("\n", style)
+			} else if v.SoftLineBreak() {
+				b.write(" ", style)
+			}
+
+		case *ast.String:
+			b.write(string(v.Value), style)
+
+		case *ast.Emphasis:
+			s := style
+			if v.Level >= 2 {
+				s.bold = true
+			} else {
+				s.italic = true
+			}
+			walkInline(v, source, s, b)
+
+		case *extast.Strikethrough:
+			s := style
+			s.strikethrough = true
+			walkInline(v, source, s, b)
+
+		case *ast.CodeSpan:
+			s := style
+			s.code = true
+			b.write(codeSpanText(v, source), s)
+
+		case *ast.Link:
+			s := style
+			s.link = string(v.Destination)
+			walkInline(v, source, s, b)
+
+		case *ast.AutoLink:
+			s := style
+			s.link = string(v.URL(source))
+			if v.AutoLinkType == ast.AutoLinkEmail && !strings.HasPrefix(s.link, "mailto:") {
+				s.link = "mailto:" + s.link
+			}
+			b.write(string(v.Label(source)), s)
+
+		case *utils.InlineMath:
+			b.writeEquation(v.Expression(source), style)
+
+		case *utils.WikiLink:
+			title := v.Title(source)
+			if id, ok := b.resolvePage(title); ok {
+				b.segments = append(b.segments, newPageMention(id, title, style))
+				b.styles = append(b.styles, style)
+				continue
+			}
+			b.unresolve(v, fmt.Sprintf("Unresolved page link [[%s]]; kept as plain text", title))
+			b.write("[["+title+"]]", style)
+
+		case *utils.Mention:
+			handle := v.Handle(source)
+			if id, ok := b.resolveUser(handle); ok {
+				b.segments = append(b.segments, newUserMention(id, handle, style))
+				b.styles = append(b.styles, style)
+				continue
+			}
+			b.unresolve(v, fmt.Sprintf("Unresolved mention @%s; kept as plain text", handle))
+			b.write("@"+handle, style)
+
+		case *ast.Image:
+			// Images only become blocks when they stand alone in a paragraph;
+			// inline ones keep their alt text, linked to the image when possible
+			s := style
+			destination := string(v.Destination)
+			if isAbsoluteURL(destination) {
+				s.link = destination
+			} else {
+				b.drop(v, fmt.Sprintf("image %q has no absolute URL; kept alt text only", destination))
+			}
+			walkInline(v, source, s, b)
+
+		case *ast.RawHTML:
+			var raw strings.Builder
+			for i := 0; i < v.Segments.Len(); i++ {
+				seg := v.Segments.At(i)
+				raw.Write(seg.Value(source))
+			}
+			tag, supported := parseInlineTag(raw.String())
+			if !supported {
+				// Report unsupported tags once, on the opening side
+				if !tag.closing {
+					b.drop(v, fmt.Sprintf("inline HTML %q stripped", raw.String()))
+				}
+				continue
+			}
+			switch {
+			case tag.name == "br":
+				b.write("\n", style)
+			case tag.closing:
+				if n := len(htmlStyles); n > 0 {
+					style = htmlStyles[n-1]
+					htmlStyles = htmlStyles[:n-1]
+				}
+			default:
+				htmlStyles = append(htmlStyles, style)
+				style = applyHTMLTag(style, tag)
+			}
+
+		default:
+			// Generic fallback for any other inline container node
+			walkInline(child, source, style, b)
+		}
+	}
+}
+
+// trimLeadingSpace drops whitespace left at the start of the first segment,
+// e.g. the gap between a task checkbox and its label.
+func trimLeadingSpace(segments []notionapi.RichText) []notionapi.RichText {
+	for len(segments) > 0 && segments[0].Text != nil {
+		segments[0].Text.Content = strings.TrimLeft(segments[0].Text.Content, " \t\n")
+		if segments[0].Text.Content != "" {
+			break
+		}
+		segments = segments[1:]
+	}
+	return segments
+}
+
+// trimTrailingSpace is the counterpart of trimLeadingSpace for the last segment.
+func trimTrailingSpace(segments []notionapi.RichText) []notionapi.RichText {
+	for n := len(segments); n > 0 && segments[n-1].Text != nil; n = len(segments) {
+		segments[n-1].Text.Content = strings.TrimRight(segments[n-1].Text.Content, " \t\n")
+		if segments[n-1].Text.Content != "" {
+			break
+		}
+		segments = segments[:n-1]
+	}
+	return segments
+}
+
+// trimLeadingText removes the first n bytes of text content, which may span
+// several segments, e.g. a marker that precedes the real content.
+func trimLeadingText(segments []notionapi.RichText, n int) []notionapi.RichText {
+	for n > 0 && len(segments) > 0 && segments[0].Text != nil {
+		content := segments[0].Text.Content
+		if len(content) > n {
+			segments[0].Text.Content = content[n:]
+			break
+		}
+		n -= len(content)
+		segments = segments[1:]
+	}
+	return segments
+}
+
+// unescapeText resolves backslash escapes and entity references in a text
+// segment the way goldmark's HTML renderer does, so `\*` or `&amp;` reach
+// Notion as the characters they stand for.
+func unescapeText(value []byte) string {
+	var sb strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '\\' && i+1 < len(value) && util.IsPunct(value[i+1]):
+			i++
+			sb.WriteByte(value[i])
+		case c == '&':
+			if r, n, ok := entityReference(value[i:]); ok {
+				sb.WriteString(r)
+				i += n - 1
+				continue
+			}
+			sb.WriteByte(c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// entityReference decodes a named or numeric character reference at the
+// start of b and returns it along with the number of bytes it spans.
+func entityReference(b []byte) (string, int, bool) {
+	end := bytes.IndexByte(b, ';')
+	if end < 2 || end > 32 {
+		return "", 0, false
+	}
+	name := string(b[1:end])
+	if strings.HasPrefix(name, "#") {
+		base, digits := 10, name[1:]
+		if strings.HasPrefix(digits, "x") || strings.HasPrefix(digits, "X") {
+			base, digits = 16, digits[1:]
+		}
+		v, err := strconv.ParseUint(digits, base, 32)
+		if err != nil || digits == "" {
+			return "", 0, false
+		}
+		if v == 0 || !utf8.ValidRune(rune(v)) {
+			v = utf8.RuneError
+		}
+		return string(rune(v)), end + 1, true
+	}
+	entity, ok := util.LookUpHTML5EntityByName(name)
+	if !ok {
+		return "", 0, false
+	}
+	return string(entity.Characters), end + 1, true
+}
+
+func codeSpanText(n *ast.CodeSpan, source []byte) string {
+	var sb strings.Builder
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		switch t := c.(type) {
+		case *ast.Text:
+			sb.Write(t.Segment.Value(source))
+		case *ast.String:
+			sb.Write(t.Value)
+		}
+	}
+	return sb.String()
+}
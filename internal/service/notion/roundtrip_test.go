@@ -0,0 +1,91 @@
+package notion
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/jomei/notionapi"
+	"github.com/obi2na/petrel/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func renderMarkdown(t *testing.T, blocks []*BlockWithChildren) string {
+	t.Helper()
+	logger.Init()
+
+	mapper := NewPetrelNotionToMarkdownMapper()
+	mapper.RegisterMappers()
+	markdown, err := mapper.Map(context.Background(), blocks)
+	require.NoError(t, err)
+	return markdown
+}
+
+func blocksJSON(t *testing.T, blocks []*BlockWithChildren) string {
+	t.Helper()
+	out, err := json.MarshalIndent(blocks, "", "  ")
+	require.NoError(t, err)
+	return string(out)
+}
+
+// TestRoundTrip checks that every fixture maps to Notion, back to Markdown,
+// and to Notion again without any change to the blocks.
+func TestRoundTrip(t *testing.T) {
+	fixtures, err := filepath.Glob("testdata/roundtrip/*.md")
+	require.NoError(t, err)
+	require.NotEmpty(t, fixtures)
+
+	for _, fixture := range fixtures {
+		t.Run(filepath.Base(fixture), func(t *testing.T) {
+			source, err := os.ReadFile(fixture)
+			require.NoError(t, err)
+
+			blocks := mapMarkdown(t, string(source))
+			markdown := renderMarkdown(t, blocks)
+			again := mapMarkdown(t, markdown)
+
+			assert.JSONEq(t, blocksJSON(t, blocks), blocksJSON(t, again), "markdown:\n%s", markdown)
+			assert.Equal(t, markdown, renderMarkdown(t, again))
+		})
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	blocks := mapMarkdown(t, "# Title\n\nSome **bold** text.\n\n- [x] done\n\n1. one\n2. two\n")
+
+	assert.Equal(t, "# Title\n\nSome **bold** text.\n\n- [x] done\n\n1. one\n2. two\n", renderMarkdown(t, blocks))
+}
+
+func TestRenderMarkdown_NestedAPIChildren(t *testing.T) {
+	// Blocks read back from the API carry their children inside the block
+	child := &notionapi.BulletedListItemBlock{
+		BasicBlock:       notionapi.BasicBlock{Object: notionapi.ObjectTypeBlock, Type: notionapi.BlockTypeBulletedListItem},
+		BulletedListItem: notionapi.ListItem{RichText: plainRichText("child")},
+	}
+	parent := &notionapi.NumberedListItemBlock{
+		BasicBlock: notionapi.BasicBlock{Object: notionapi.ObjectTypeBlock, Type: notionapi.BlockTypeNumberedListItem},
+		NumberedListItem: notionapi.ListItem{
+			RichText: plainRichText("parent"),
+			Children: notionapi.Blocks{child},
+		},
+	}
+
+	markdown := renderMarkdown(t, []*BlockWithChildren{{Block: parent}})
+	assert.Equal(t, "1. parent\n   - child\n", markdown)
+}
+
+func TestRenderMarkdown_CustomCallout(t *testing.T) {
+	emoji := notionapi.Emoji("🚀")
+	callout := &notionapi.CalloutBlock{
+		BasicBlock: notionapi.BasicBlock{Object: notionapi.ObjectTypeBlock, Type: notionapi.BlockTypeCallout},
+		Callout: notionapi.Callout{
+			RichText: plainRichText("Launch day"),
+			Icon:     &notionapi.Icon{Type: "emoji", Emoji: &emoji},
+		},
+	}
+
+	markdown := renderMarkdown(t, []*BlockWithChildren{{Block: callout}})
+	assert.Equal(t, "> 🚀 Launch day\n", markdown)
+}
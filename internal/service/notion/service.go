@@ -338,11 +338,17 @@ func (s *NotionDatabaseService) UserHasWorkspace(ctx context.Context, userID uui
 		return petrelmodels.UserIntegration{}, false
 	}
 	return petrelmodels.UserIntegration{
-		Token:        integration.AccessToken,
-		DraftsRepoID: integration.DraftsPageID.String,
+		IntegrationID: integration.ID,
+		Token:         integration.AccessToken,
+		DraftsRepoID:  integration.DraftsPageID.String,
 	}, true
 }
 
+// SaveDraft records a draft page staged in Notion.
+func (s *NotionDatabaseService) SaveDraft(ctx context.Context, params models.CreateNotionDraftParams) (models.NotionDraft, error) {
+	return s.DB.CreateNotionDraft(ctx, params)
+}
+
 func (s *NotionDatabaseService) IsValidDraftPage(ctx context.Context, userID uuid.UUID, pageID string) (bool, error) {
 	validNotionDraftPageParams := models.IsValidNotionDraftPageParams{
 		UserID:       userID,
@@ -432,72 +438,199 @@ func (s *NotionIntegrationService[T]) CompleteOAuth(ctx context.Context, code, s
 // Notion Draft Service starts here
 
 type DraftService interface {
-	StageDraft(ctx context.Context, userID uuid.UUID, notionDestinations []petrelmodels.ValidatedDestination, doc ast.Node, source []byte) ([]petrelmodels.DraftResultEntry, error)
+	StageDraft(ctx context.Context, userID uuid.UUID, notionDestinations []petrelmodels.ValidatedDestination, doc ast.Node, source []byte, opts petrelmodels.StageOptions) ([]petrelmodels.DraftResultEntry, error)
+}
+
+// ErrContentDropped is returned in strict mode when part of the draft could not be mapped to Notion.
+var ErrContentDropped = errors.New("markdown content could not be mapped to Notion")
+
+// DraftStore records the drafts staged in Notion.
+type DraftStore interface {
+	SaveDraft(ctx context.Context, params models.CreateNotionDraftParams) (models.NotionDraft, error)
 }
 
 type NotionDraftService struct {
 	NotionClient utils.NotionApiClient
 	Mapper       MarkdownToNotionMapper
+	LinkRules    *LinkRuleSet
+	Drafts       DraftStore // nil to skip recording drafts
 }
 
-func NewNotionDraftService(notionClient utils.NotionApiClient, notionMapper *PetrelMarkdownToNotionMapper) *NotionDraftService {
+func NewNotionDraftService(notionClient utils.NotionApiClient, notionMapper *PetrelMarkdownToNotionMapper, linkRules *LinkRuleSet,
+	drafts DraftStore) *NotionDraftService {
 	return &NotionDraftService{
 		NotionClient: notionClient,
 		Mapper:       notionMapper,
+		LinkRules:    linkRules,
+		Drafts:       drafts,
 	}
 }
 
+// Capabilities is what drafts staged by s carry over from Markdown.
+func (s *NotionDraftService) Capabilities() utils.PlatformCapabilities {
+	return s.Mapper.Capabilities()
+}
+
 func (s *NotionDraftService) StageDraft(ctx context.Context, userID uuid.UUID, notionDestinations []petrelmodels.ValidatedDestination,
-	doc ast.Node, source []byte) ([]petrelmodels.DraftResultEntry, error) {
+	doc ast.Node, source []byte, opts petrelmodels.StageOptions) ([]petrelmodels.DraftResultEntry, error) {
 	var results []petrelmodels.DraftResultEntry
 
-	// Map AST -> Notion blocks
-	blockTree, err := s.Mapper.Map(ctx, doc, source)
-	if err != nil {
-		return nil, err
+	// Map AST -> Notion blocks. Wiki-links and mentions resolve against each
+	// workspace, so every destination gets its own mapping.
+	mapped := make([]mappedDraft, len(notionDestinations))
+	lossy := 0
+	for i, dest := range notionDestinations {
+		resolver := NewNotionReferenceResolver(s.NotionClient, dest.Token)
+		blockTree, report, err := s.Mapper.Map(ctx, doc, source, MapOptions{
+			Resolver:  resolver,
+			LinkRules: s.LinkRules.ForWorkspace(dest.Workspace),
+		})
+		if err != nil {
+			return nil, err
+		}
+		mapped[i] = mappedDraft{blockTree: blockTree, warnings: report.Warnings}
+		if !report.Empty() {
+			mapped[i].report = &report
+			lossy++
+		}
+	}
+
+	if opts.Strict && lossy > 0 {
+		err := fmt.Errorf("%w in %d destination(s)", ErrContentDropped, lossy)
+		for i, dest := range notionDestinations {
+			entry := petrelmodels.DraftResultEntry{
+				Platform:      "notion",
+				WorkspaceID:   dest.Workspace,
+				Status:        "fail",
+				LintWarnings:  mapped[i].warnings,
+				MappingReport: mapped[i].report,
+			}
+			if report := mapped[i].report; report != nil {
+				entry.ErrorMessage = fmt.Sprintf("%s: %d node(s) dropped, %d mapping error(s)",
+					ErrContentDropped, len(report.Dropped), len(report.Errors))
+			} else {
+				entry.ErrorMessage = "not staged: content was dropped for another destination"
+			}
+			results = append(results, entry)
+		}
+		logger.With(ctx).Warn("Strict mode: not staging draft with unmapped content", zap.Error(err))
+		return results, err
 	}
-	// Flatten and transform each BlockWithChildren -> []notionapi.Block
-	blocks := flattenBlockTree(blockTree)
 
 	// iterate through notion workspaces
-	for _, dest := range notionDestinations {
+	for i, dest := range notionDestinations {
 		var page *notionapi.Page
 		var err error
 
 		if dest.Append {
 			// TODO: append blocks to existing page
 		} else {
-			page, err = s.createNewDraftPage(ctx, dest.Token, dest.DraftsRepoID, blocks)
+			page, err = s.createNewDraftPage(ctx, dest.Token, dest.DraftsRepoID, opts.Title, mapped[i].blockTree)
 		}
 
 		if err != nil {
 			results = append(results, petrelmodels.DraftResultEntry{
-				Platform:     "notion",
-				WorkspaceID:  dest.Workspace,
-				PageID:       "",
-				Status:       "fail",
-				ErrorMessage: err.Error(),
+				Platform:      "notion",
+				WorkspaceID:   dest.Workspace,
+				PageID:        "",
+				Status:        "fail",
+				ErrorMessage:  err.Error(),
+				LintWarnings:  mapped[i].warnings,
+				MappingReport: mapped[i].report,
 			})
 
 			logger.With(ctx).Error("Error pushing to notion", zap.Error(err))
 			return results, err
 		}
 
+		draftID, err := s.recordDraft(ctx, userID, dest, page, opts)
+		if err != nil {
+			// The page is in Notion either way, so the draft still counts as staged
+			logger.With(ctx).Error("Failed to record draft", zap.String("page_id", page.ID.String()), zap.Error(err))
+		}
+
 		results = append(results, petrelmodels.DraftResultEntry{
-			Platform:     "notion",
-			WorkspaceID:  dest.Workspace,
-			PageID:       page.ID.String(),
-			URL:          page.URL,
-			Status:       "draft",
-			Action:       "created",
-			LintWarnings: nil,
+			DraftID:       draftID,
+			Platform:      "notion",
+			WorkspaceID:   dest.Workspace,
+			PageID:        page.ID.String(),
+			URL:           page.URL,
+			Status:        "draft",
+			Action:        "created",
+			LintWarnings:  mapped[i].warnings,
+			MappingReport: mapped[i].report,
 		})
 	}
 
 	return results, nil
 }
 
-func (s *NotionDraftService) createNewDraftPage(ctx context.Context, token, draftsRepoID string, children []notionapi.Block) (*notionapi.Page, error) {
+// recordDraft stores the staged page with its metrics and returns the
+// record's ID, or "" when drafts are not recorded.
+func (s *NotionDraftService) recordDraft(ctx context.Context, userID uuid.UUID, dest petrelmodels.ValidatedDestination,
+	page *notionapi.Page, opts petrelmodels.StageOptions) (string, error) {
+	if s.Drafts == nil {
+		return "", nil
+	}
+	var metrics []byte
+	if opts.Metrics != nil {
+		var err error
+		if metrics, err = json.Marshal(opts.Metrics); err != nil {
+			return "", fmt.Errorf("failed to encode draft metrics: %w", err)
+		}
+	}
+	draft, err := s.Drafts.SaveDraft(ctx, models.CreateNotionDraftParams{
+		ID:                  uuid.New(),
+		UserID:              userID,
+		NotionIntegrationID: dest.IntegrationID,
+		NotionPageID:        page.ID.String(),
+		Title:               pgtype.Text{String: opts.Title, Valid: opts.Title != ""},
+		Status:              models.NullDraftStatus{DraftStatus: models.DraftStatusDraft, Valid: true},
+		IsOrphaned:          pgtype.Bool{Bool: false, Valid: true},
+		Metrics:             metrics,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to save draft: %w", err)
+	}
+	return draft.ID.String(), nil
+}
+
+// mappedDraft is the draft as mapped for one destination.
+type mappedDraft struct {
+	blockTree []*BlockWithChildren
+	report    *petrelmodels.MappingReport // nil when nothing was lost
+	warnings  []utils.LintWarning
+}
+
+// Notion API limits for a single create or append request
+const (
+	maxChildrenPerRequest = 100
+	maxNestingPerRequest  = 2
+	maxBlocksPerRequest   = 1000
+)
+
+// createNewDraftPage creates the draft page with as many leading blocks as
+// fit in one request, then appends the rest in order.
+func (s *NotionDraftService) createNewDraftPage(ctx context.Context, token, draftsRepoID, title string, tree []*BlockWithChildren) (*notionapi.Page, error) {
+	if title == "" {
+		title = "Draft from Petrel"
+	}
+
+	// Page creation does not return the IDs of its children, so only blocks
+	// whose whole subtree fits can go into the create request
+	var children []notionapi.Block
+	total := 0
+	first := 0
+	for ; first < len(tree) && first < maxChildrenPerRequest; first++ {
+		block, deferred := toRequestBlock(tree[first], maxNestingPerRequest, maxBlocksPerRequest-total, s.Mapper.SetChildren)
+		size := countBlocks(tree[first])
+		if len(deferred) > 0 || total+size > maxBlocksPerRequest {
+			break
+		}
+		children = append(children, block)
+		total += size
+	}
+
 	req := &notionapi.PageCreateRequest{
 		Parent: notionapi.Parent{
 			Type:   notionapi.ParentTypePageID,
@@ -508,7 +641,7 @@ func (s *NotionDraftService) createNewDraftPage(ctx context.Context, token, draf
 				Title: []notionapi.RichText{
 					{
 						Text: &notionapi.Text{
-							Content: "Draft from Petrel",
+							Content: title,
 						},
 					},
 				},
@@ -516,26 +649,144 @@ func (s *NotionDraftService) createNewDraftPage(ctx context.Context, token, draf
 		},
 		Children: children,
 	}
-	return s.NotionClient.CreatePage(ctx, token, req)
+	page, err := s.NotionClient.CreatePage(ctx, token, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if first < len(tree) {
+		logger.With(ctx).Info("Draft exceeds a single Notion request, appending remaining blocks",
+			zap.Int("created_with", first), zap.Int("total_top_level", len(tree)))
+		if err := s.appendBlocks(ctx, token, notionapi.BlockID(page.ID), tree[first:]); err != nil {
+			return page, fmt.Errorf("page %s created but appending blocks failed: %w", page.ID, err)
+		}
+	}
+	return page, nil
 }
 
-func flattenBlockTree(tree []*BlockWithChildren) []notionapi.Block {
-	var blocks []notionapi.Block
+// appendBlocks appends nodes under parentID in batches that respect Notion's
+// request limits. Content nested too deeply for one request is appended to
+// its parent block once that block's ID is known.
+func (s *NotionDraftService) appendBlocks(ctx context.Context, token string, parentID notionapi.BlockID, nodes []*BlockWithChildren) error {
+	for start := 0; start < len(nodes); {
+		var batch []notionapi.Block
+		var deferred [][]*BlockWithChildren
+		total := 0
+		end := start
+		for ; end < len(nodes) && len(batch) < maxChildrenPerRequest; end++ {
+			block, rest := toRequestBlock(nodes[end], maxNestingPerRequest, maxBlocksPerRequest-total, s.Mapper.SetChildren)
+			size := countBlocks(nodes[end]) - countAll(rest)
+			if len(batch) > 0 && total+size > maxBlocksPerRequest {
+				break
+			}
+			batch = append(batch, block)
+			deferred = append(deferred, rest)
+			total += size
+		}
+
+		resp, err := s.NotionClient.AppendBlockChildren(ctx, token, parentID, &notionapi.AppendBlockChildrenRequest{
+			Children: batch,
+		})
+		if err != nil {
+			logger.With(ctx).Error("failed to append blocks", zap.Error(err), zap.String("parent_id", parentID.String()))
+			return err
+		}
+		if len(resp.Results) != len(batch) {
+			return fmt.Errorf("notion returned %d blocks for %d appended", len(resp.Results), len(batch))
+		}
 
-	// walk tree and recursively flatten
-	func(nodes []*BlockWithChildren) {
-		for _, b := range nodes {
-			if len(b.Children) > 0 {
-				setChildren(b.Block, flattenBlockTree(b.Children))
+		for i, rest := range deferred {
+			if len(rest) == 0 {
+				continue
+			}
+			if err := s.appendBlocks(ctx, token, resp.Results[i].GetID(), rest); err != nil {
+				return err
 			}
-			blocks = append(blocks, b.Block)
 		}
-	}(tree)
+		start = end
+	}
+	return nil
+}
+
+// toRequestBlock prepares node for a request that allows depth more levels
+// of nesting below it and budget more blocks, node included. Leading
+// children whose subtrees fit are attached; the first child that does not
+// fit and everything after it are returned so they can be appended once the
+// block exists.
+//
+// A child is never sent without some of its own children: it is deferred
+// whole instead. Notion only creates tables together with their rows, so a
+// table nested too deeply waits for the next append call with its rows.
+func toRequestBlock(node *BlockWithChildren, depth, budget int, set ChildrenSetter) (notionapi.Block, []*BlockWithChildren) {
+	if depth == 0 || len(node.Children) == 0 {
+		set(node.Block, nil)
+		return withListStart(node), node.Children
+	}
 
-	return blocks
+	var children []notionapi.Block
+	used := 1 // node itself
+	i := 0
+	for ; i < len(node.Children) && i < maxChildrenPerRequest; i++ {
+		size := countBlocks(node.Children[i])
+		if used+size > budget {
+			break
+		}
+		child, rest := toRequestBlock(node.Children[i], depth-1, budget-used, set)
+		if len(rest) > 0 {
+			break // defer the child whole, not just the children that did not fit
+		}
+		children = append(children, child)
+		used += size
+	}
+
+	set(node.Block, children)
+	return withListStart(node), node.Children[i:]
+}
+
+// numberedListStart is a numbered list item that starts its list at a
+// number other than 1. notionapi has no field for list_start_index.
+type numberedListStart struct {
+	*notionapi.NumberedListItemBlock
+	start int
+}
+
+func (b numberedListStart) MarshalJSON() ([]byte, error) {
+	type listItem struct {
+		notionapi.ListItem
+		ListStartIndex int `json:"list_start_index"`
+	}
+	return json.Marshal(struct {
+		notionapi.BasicBlock
+		NumberedListItem listItem `json:"numbered_list_item"`
+	}{
+		BasicBlock:       b.BasicBlock,
+		NumberedListItem: listItem{ListItem: b.NumberedListItem, ListStartIndex: b.start},
+	})
 }
 
-func setChildren(block notionapi.Block, children []notionapi.Block) {
+func withListStart(node *BlockWithChildren) notionapi.Block {
+	if item, ok := node.Block.(*notionapi.NumberedListItemBlock); ok && node.ListStart > 1 {
+		return numberedListStart{NumberedListItemBlock: item, start: node.ListStart}
+	}
+	return node.Block
+}
+
+// countBlocks counts node and all of its descendants.
+func countBlocks(node *BlockWithChildren) int {
+	return 1 + countAll(node.Children)
+}
+
+func countAll(nodes []*BlockWithChildren) int {
+	total := 0
+	for _, n := range nodes {
+		total += countBlocks(n)
+	}
+	return total
+}
+
+// setChildren attaches children to the block types that Notion lets hold
+// blocks and reports whether block is one of them.
+func setChildren(block notionapi.Block, children []notionapi.Block) bool {
 	switch b := block.(type) {
 	case *notionapi.ToggleBlock:
 		b.Toggle.Children = children
@@ -545,7 +796,22 @@ func setChildren(block notionapi.Block, children []notionapi.Block) {
 		b.NumberedListItem.Children = children
 	case *notionapi.QuoteBlock:
 		b.Quote.Children = children
+	case *notionapi.TableBlock:
+		b.Table.Children = children
+	case *notionapi.ToDoBlock:
+		b.ToDo.Children = children
+	case *notionapi.CalloutBlock:
+		b.Callout.Children = children
+	case *notionapi.ColumnListBlock:
+		b.ColumnList.Children = children
+	case *notionapi.ColumnBlock:
+		b.Column.Children = children
+	case *notionapi.SyncedBlock:
+		b.SyncedBlock.Children = children
+	default:
+		return false
 	}
+	return true
 }
 
 // Notion Draft Service ends here
@@ -0,0 +1,349 @@
+package notion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jomei/notionapi"
+	"github.com/obi2na/petrel/internal/db/models"
+	"github.com/obi2na/petrel/internal/logger"
+	petrelmodels "github.com/obi2na/petrel/internal/models"
+	"github.com/obi2na/petrel/internal/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+type appendCall struct {
+	parentID notionapi.BlockID
+	children []notionapi.Block
+}
+
+// fakeNotionClient records requests and hands out sequential block IDs.
+type fakeNotionClient struct {
+	created   *notionapi.PageCreateRequest
+	appends   []appendCall
+	nextID    int
+	pages     map[string]notionapi.ObjectID // workspace pages by title
+	users     []notionapi.User
+	usersErr  error
+	userLists int
+	searches  int
+}
+
+func (f *fakeNotionClient) CreatePage(ctx context.Context, token string, req *notionapi.PageCreateRequest) (*notionapi.Page, error) {
+	f.created = req
+	return &notionapi.Page{ID: "page-1", URL: "https://notion.so/page-1"}, nil
+}
+
+func (f *fakeNotionClient) AppendBlockChildren(ctx context.Context, token string, blockID notionapi.BlockID, req *notionapi.AppendBlockChildrenRequest) (*notionapi.AppendBlockChildrenResponse, error) {
+	f.appends = append(f.appends, appendCall{parentID: blockID, children: req.Children})
+	resp := &notionapi.AppendBlockChildrenResponse{}
+	for range req.Children {
+		f.nextID++
+		resp.Results = append(resp.Results, &notionapi.ParagraphBlock{
+			BasicBlock: notionapi.BasicBlock{ID: notionapi.BlockID(fmt.Sprintf("block-%d", f.nextID))},
+		})
+	}
+	return resp, nil
+}
+
+func (f *fakeNotionClient) Search(ctx context.Context, token string, req *notionapi.SearchRequest) (*notionapi.SearchResponse, error) {
+	f.searches++
+	resp := &notionapi.SearchResponse{}
+	for title, id := range f.pages {
+		if !strings.Contains(strings.ToLower(title), strings.ToLower(req.Query)) {
+			continue
+		}
+		resp.Results = append(resp.Results, &notionapi.Page{
+			ID: id,
+			Properties: notionapi.Properties{
+				"title": &notionapi.TitleProperty{Title: plainRichText(title)},
+			},
+		})
+	}
+	return resp, nil
+}
+
+func (f *fakeNotionClient) ListUsers(ctx context.Context, token string, pagination *notionapi.Pagination) (*notionapi.UsersListResponse, error) {
+	f.userLists++
+	if f.usersErr != nil {
+		return nil, f.usersErr
+	}
+	return &notionapi.UsersListResponse{Results: f.users}, nil
+}
+
+func stageMarkdown(t *testing.T, client *fakeNotionClient, markdown string) []petrelmodels.DraftResultEntry {
+	t.Helper()
+	results, err := stageMarkdownWithOptions(t, client, markdown, petrelmodels.StageOptions{})
+	require.NoError(t, err)
+	return results
+}
+
+func stageMarkdownWithOptions(t *testing.T, client *fakeNotionClient, markdown string, opts petrelmodels.StageOptions) ([]petrelmodels.DraftResultEntry, error) {
+	t.Helper()
+	logger.Init()
+
+	mapper := NewPetrelMarkdownToNotionMapper()
+	mapper.RegisterMappers()
+	svc := NewNotionDraftService(client, mapper, nil, nil)
+
+	doc, source, err := newTestParser().Parse(markdown)
+	require.NoError(t, err)
+
+	destinations := []petrelmodels.ValidatedDestination{{
+		UserIntegration: petrelmodels.UserIntegration{Token: "token", DraftsRepoID: "drafts-repo"},
+		Workspace:       "workspace-1",
+	}}
+	return svc.StageDraft(context.Background(), uuid.New(), destinations, doc, source, opts)
+}
+
+func TestStageDraft_BatchesTopLevelBlocks(t *testing.T) {
+	var sb strings.Builder
+	for i := 0; i < 250; i++ {
+		fmt.Fprintf(&sb, "Paragraph %d\n\n", i)
+	}
+
+	client := &fakeNotionClient{}
+	results := stageMarkdown(t, client, sb.String())
+	require.Len(t, results, 1)
+	assert.Equal(t, "page-1", results[0].PageID)
+
+	require.NotNil(t, client.created)
+	assert.Len(t, client.created.Children, maxChildrenPerRequest)
+	require.Len(t, client.appends, 2)
+	assert.Equal(t, notionapi.BlockID("page-1"), client.appends[0].parentID)
+	assert.Len(t, client.appends[0].children, 100)
+	assert.Len(t, client.appends[1].children, 50)
+
+	last := client.appends[1].children[49].(*notionapi.ParagraphBlock)
+	assert.Equal(t, "Paragraph 249", last.Paragraph.RichText[0].Text.Content)
+}
+
+func TestStageDraft_DefersDeepNesting(t *testing.T) {
+	markdown := "Intro\n\n- level 1\n  - level 2\n    - level 3\n      - level 4\n"
+
+	client := &fakeNotionClient{}
+	stageMarkdown(t, client, markdown)
+
+	// The list cannot be created with the page because it nests too deeply
+	require.NotNil(t, client.created)
+	require.Len(t, client.created.Children, 1)
+
+	require.Len(t, client.appends, 2)
+	assert.Equal(t, notionapi.BlockID("page-1"), client.appends[0].parentID)
+	require.Len(t, client.appends[0].children, 1)
+	level1 := client.appends[0].children[0].(*notionapi.BulletedListItemBlock)
+	assert.Empty(t, level1.BulletedListItem.Children)
+
+	// Level 2 onwards is appended under the level 1 block's returned ID
+	assert.Equal(t, notionapi.BlockID("block-1"), client.appends[1].parentID)
+	require.Len(t, client.appends[1].children, 1)
+	level2 := client.appends[1].children[0].(*notionapi.BulletedListItemBlock)
+	require.Len(t, level2.BulletedListItem.Children, 1)
+	level3 := level2.BulletedListItem.Children[0].(*notionapi.BulletedListItemBlock)
+	assert.Len(t, level3.BulletedListItem.Children, 1)
+}
+
+func TestStageDraft_DefersNestedTableWithItsRows(t *testing.T) {
+	markdown := "Intro\n\n- item\n  > Quote\n  >\n  > | a | b |\n  > | --- | --- |\n  > | 1 | 2 |\n"
+
+	client := &fakeNotionClient{}
+	stageMarkdown(t, client, markdown)
+
+	// The table sits two levels below the list item, so it cannot go out
+	// with the item; it goes out with its quote, rows and all
+	require.NotNil(t, client.created)
+	require.Len(t, client.created.Children, 1)
+	require.Len(t, client.appends, 2)
+	item := client.appends[0].children[0].(*notionapi.BulletedListItemBlock)
+	assert.Empty(t, item.BulletedListItem.Children)
+
+	assert.Equal(t, notionapi.BlockID("block-1"), client.appends[1].parentID)
+	quote := client.appends[1].children[0].(*notionapi.QuoteBlock)
+	require.Len(t, quote.Quote.Children, 1)
+	table := quote.Quote.Children[0].(*notionapi.TableBlock)
+	assert.Len(t, table.Table.Children, 2)
+}
+
+func TestStageDraft_SplitsSubtreeOverBlockLimit(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("- parent\n")
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&sb, "  - item %d\n", i)
+		for j := 0; j < 10; j++ {
+			fmt.Fprintf(&sb, "    - leaf %d.%d\n", i, j)
+		}
+	}
+
+	client := &fakeNotionClient{}
+	stageMarkdown(t, client, sb.String())
+
+	// 1,101 blocks under one list item: the item goes out with as many
+	// sub-items as fit, and the rest are appended under it
+	require.NotNil(t, client.created)
+	assert.Empty(t, client.created.Children)
+	require.Len(t, client.appends, 2)
+	total := 0
+	for _, call := range client.appends {
+		n := countListBlocks(call.children)
+		assert.LessOrEqual(t, n, maxBlocksPerRequest)
+		total += n
+	}
+	assert.Equal(t, 1101, total)
+
+	parent := client.appends[0].children[0].(*notionapi.BulletedListItemBlock)
+	assert.Len(t, parent.BulletedListItem.Children, 90)
+	assert.Equal(t, notionapi.BlockID("block-1"), client.appends[1].parentID)
+	assert.Len(t, client.appends[1].children, 10)
+}
+
+// countListBlocks counts bulleted list items and their nested items.
+func countListBlocks(blocks []notionapi.Block) int {
+	total := 0
+	for _, b := range blocks {
+		total++
+		if item, ok := b.(*notionapi.BulletedListItemBlock); ok {
+			total += countListBlocks(item.BulletedListItem.Children)
+		}
+	}
+	return total
+}
+
+func TestStageDraft_SendsListStartIndex(t *testing.T) {
+	client := &fakeNotionClient{}
+	stageMarkdown(t, client, "3. three\n4. four\n")
+
+	require.NotNil(t, client.created)
+	require.Len(t, client.created.Children, 2)
+	first, err := json.Marshal(client.created.Children[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(first), `"list_start_index":3`)
+	assert.Contains(t, string(first), `"content":"three"`)
+	second, err := json.Marshal(client.created.Children[1])
+	require.NoError(t, err)
+	assert.NotContains(t, string(second), "list_start_index")
+}
+
+func TestSplitLongRichText(t *testing.T) {
+	long := strings.Repeat("a", maxRichTextLength*2+10)
+	segments := plainRichText(long)
+	require.Len(t, segments, 3)
+	assert.Len(t, segments[0].Text.Content, maxRichTextLength)
+	assert.Len(t, segments[1].Text.Content, maxRichTextLength)
+	assert.Len(t, segments[2].Text.Content, 10)
+
+	// Characters outside the BMP count twice and are never split
+	emoji := strings.Repeat("😀", maxRichTextLength)
+	segments = plainRichText(emoji)
+	require.Len(t, segments, 2)
+	assert.Equal(t, maxRichTextLength/2, len([]rune(segments[0].Text.Content)))
+}
+
+func TestStageDraft_ReportsDroppedContent(t *testing.T) {
+	markdown := "# Title\n\nSee ![logo](logo.png).\n\nText with <span>html</span>.\n"
+
+	client := &fakeNotionClient{}
+	results := stageMarkdown(t, client, markdown)
+	require.Len(t, results, 1)
+	assert.Equal(t, "draft", results[0].Status)
+
+	report := results[0].MappingReport
+	require.NotNil(t, report)
+	assert.Equal(t, []petrelmodels.DroppedNode{
+		{Kind: "Image", Line: 3, Reason: `image "logo.png" has no absolute URL; kept alt text only`},
+		{Kind: "RawHTML", Line: 5, Reason: `inline HTML "<span>" stripped`},
+	}, report.Dropped)
+	assert.Empty(t, report.Errors)
+}
+
+func TestStageDraft_StrictModeBlocksDroppedContent(t *testing.T) {
+	client := &fakeNotionClient{}
+	results, err := stageMarkdownWithOptions(t, client, "Text\n\n![logo](logo.png)\n", petrelmodels.StageOptions{Strict: true})
+
+	require.ErrorIs(t, err, ErrContentDropped)
+	assert.Nil(t, client.created, "no page should be created in strict mode")
+	require.Len(t, results, 1)
+	assert.Equal(t, "fail", results[0].Status)
+	require.NotNil(t, results[0].MappingReport)
+	assert.Len(t, results[0].MappingReport.Dropped, 1)
+}
+
+func TestStageDraft_StrictModeAllowsCleanDraft(t *testing.T) {
+	client := &fakeNotionClient{}
+	results, err := stageMarkdownWithOptions(t, client, "# Title\n\n| a | b |\n| - | - |\n| 1 | 2 |\n", petrelmodels.StageOptions{Strict: true})
+
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Nil(t, results[0].MappingReport)
+	assert.NotNil(t, client.created)
+}
+
+type fakeDraftStore struct {
+	saved []models.CreateNotionDraftParams
+	err   error
+}
+
+func (f *fakeDraftStore) SaveDraft(ctx context.Context, params models.CreateNotionDraftParams) (models.NotionDraft, error) {
+	if f.err != nil {
+		return models.NotionDraft{}, f.err
+	}
+	f.saved = append(f.saved, params)
+	return models.NotionDraft{ID: params.ID}, nil
+}
+
+func TestStageDraft_RecordsDraftWithMetrics(t *testing.T) {
+	logger.Init()
+	mapper := NewPetrelMarkdownToNotionMapper()
+	mapper.RegisterMappers()
+	store := &fakeDraftStore{}
+	svc := NewNotionDraftService(&fakeNotionClient{}, mapper, nil, store)
+
+	doc, source, err := newTestParser().Parse("# Title\n\nShort and plain.\n")
+	require.NoError(t, err)
+	metrics := utils.ComputeDraftMetrics(doc, source)
+	integrationID := uuid.New()
+	userID := uuid.New()
+	destinations := []petrelmodels.ValidatedDestination{{
+		UserIntegration: petrelmodels.UserIntegration{IntegrationID: integrationID, Token: "token", DraftsRepoID: "drafts-repo"},
+		Workspace:       "workspace-1",
+	}}
+
+	results, err := svc.StageDraft(context.Background(), userID, destinations, doc, source,
+		petrelmodels.StageOptions{Title: "Title", Metrics: &metrics})
+	require.NoError(t, err)
+	require.Len(t, store.saved, 1)
+	saved := store.saved[0]
+	assert.Equal(t, userID, saved.UserID)
+	assert.Equal(t, integrationID, saved.NotionIntegrationID)
+	assert.Equal(t, "page-1", saved.NotionPageID)
+	assert.Equal(t, "Title", saved.Title.String)
+	assert.Equal(t, models.DraftStatusDraft, saved.Status.DraftStatus)
+
+	var stored utils.DraftMetrics
+	require.NoError(t, json.Unmarshal(saved.Metrics, &stored))
+	assert.Equal(t, metrics, stored)
+	require.Len(t, results, 1)
+	assert.Equal(t, saved.ID.String(), results[0].DraftID)
+
+	// A page that made it to Notion stays staged when recording it fails
+	store.err = errors.New("db down")
+	results, err = svc.StageDraft(context.Background(), userID, destinations, doc, source, petrelmodels.StageOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "draft", results[0].Status)
+	assert.Empty(t, results[0].DraftID)
+}
+
+func TestStageDraft_UnresolvedReferencesAreWarnings(t *testing.T) {
+	client := &fakeNotionClient{pages: map[string]notionapi.ObjectID{"Roadmap": "page-roadmap"}}
+	results, err := stageMarkdownWithOptions(t, client, "See [[Roadmap]] and [[Nowhere]].\n", petrelmodels.StageOptions{Strict: true})
+
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Nil(t, results[0].MappingReport)
+	require.Len(t, results[0].LintWarnings, 1)
+	assert.Equal(t, "Unresolved page link [[Nowhere]]; kept as plain text", results[0].LintWarnings[0].Message)
+}